@@ -10,9 +10,17 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/api/handlers"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/api/middleware"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/database"
-	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/events"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs/builtin"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue/backend"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/logger"
 )
@@ -21,43 +29,72 @@ func main() {
 	cfg := config.Load()
 	slog := logger.New(cfg.LogLevel)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg, "jobs-api")
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	sqlDB, _ := db.DB()
 	defer sqlDB.Close()
+	if err := metrics.RegisterDB(sqlDB, "jobsdb"); err != nil {
+		log.Fatalf("Failed to register database metrics: %v", err)
+	}
 
-	if err := database.Migrate(db); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if cfg.DatabaseAutoMigrate {
+		if err := database.Migrate(db); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+	// Refuse to serve a schema this binary doesn't match, e.g. one a newer
+	// release has already migrated
+	if err := database.CheckSchema(db); err != nil {
+		log.Fatalf("Database schema check failed: %v", err)
 	}
 
-	sqsClient, err := queue.NewSQSClient(cfg)
+	jobQueue, err := backend.New(cfg, db)
 	if err != nil {
-		log.Fatalf("Failed to create SQS client: %v", err)
+		log.Fatalf("Failed to create queue: %v", err)
+	}
+
+	if err := builtin.RegisterSchemas(jobs.Default); err != nil {
+		log.Fatalf("Failed to register job schemas: %v", err)
 	}
 
+	// Job status changes, announced by the database whichever process makes
+	// them, for the event streams
+	broker := events.NewBroker()
+	listenerCtx, stopListener := context.WithCancel(context.Background())
+	defer stopListener()
+	go func() {
+		if err := events.NewListener(cfg.DatabaseURL, broker, slog).Start(listenerCtx); err != nil {
+			slog.Error("Job event listener failed", "error", err)
+		}
+	}()
+
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(gin.Logger())
-
-	h := handlers.New(db, sqsClient, slog)
-
-	router.GET("/health", h.Health)
-
-	// API routes with /api prefix for ALB routing
-	api := router.Group("/api")
-	{
-		api.GET("/health", h.Health)
-		api.POST("/jobs", h.CreateJob)
-		api.GET("/jobs/:id", h.GetJob)
-		api.GET("/jobs", h.ListJobs)
+	router.Use(middleware.Metrics())
+	router.Use(otelgin.Middleware("jobs-api"))
+
+	h := handlers.New(db, jobQueue, slog).WithEvents(broker)
+	if cfg.WebhookSecret != "" {
+		h.WithCallbacks()
+	} else {
+		slog.Warn("WEBHOOK_SECRET is not set, jobs with a callback_url are rejected")
 	}
+	h.RegisterRoutes(router)
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: router,
 	}
+	// Event streams never end on their own, so close them for Shutdown
+	srv.RegisterOnShutdown(broker.Close)
 
 	go func() {
 		slog.Info("Starting API server", "port", cfg.Port)
@@ -77,6 +114,10 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	stopListener()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 
 	slog.Info("Server exited")
 }
\ No newline at end of file
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/database"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/dlq"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue/backend"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/logger"
+)
+
+const usage = `Usage: jobctl <command> [arguments]
+
+Commands:
+  dlq list [-limit N] [-json]      Show dead-lettered messages with their jobs
+  dlq redrive <message-id>...      Move messages back to the main queue
+  migrate up                       Apply all pending schema migrations
+  migrate down                     Revert the last applied migration
+  migrate to <version>             Migrate up or down to a version (0 reverts all)
+  migrate status                   Show known and applied migrations
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+
+	var err error
+	switch os.Args[1] {
+	case "dlq":
+		err = runDLQ(cfg, os.Args[2:])
+	case "migrate":
+		err = runMigrate(cfg, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("jobctl %s: %v", os.Args[1], err)
+	}
+}
+
+func runDLQ(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	jobQueue, err := backend.New(cfg, db)
+	if err != nil {
+		return err
+	}
+	dlqQueue, ok := jobQueue.(interfaces.DeadLetterQueue)
+	if !ok {
+		return queue.ErrNoDeadLetterQueue
+	}
+
+	service := dlq.NewService(dlqQueue, repository.NewJobRepository(db), logger.New(cfg.LogLevel))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("dlq list", flag.ExitOnError)
+		limit := fs.Int("limit", 10, "maximum number of messages to show")
+		asJSON := fs.Bool("json", false, "print JSON instead of a table")
+		fs.Parse(args[1:])
+
+		entries, err := service.List(ctx, *limit)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return json.NewEncoder(os.Stdout).Encode(entries)
+		}
+		return printDeadLetters(entries)
+
+	case "redrive":
+		if len(args) < 2 {
+			return fmt.Errorf("redrive needs at least one message ID")
+		}
+		moved, err := service.Redrive(ctx, args[1:])
+		for _, letter := range moved {
+			fmt.Printf("redriven %s (job %s)\n", letter.MessageID, letter.JobID)
+		}
+		if err != nil {
+			return err
+		}
+		if len(moved) < len(args[1:]) {
+			fmt.Fprintf(os.Stderr, "%d of %d messages not redriven: not found in the DLQ, or their job is finished or running\n", len(args[1:])-len(moved), len(args[1:]))
+		}
+		return nil
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	return nil
+}
+
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	migrator, err := database.NewMigrator(sqlDB)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var applied []database.Migration
+	direction := "applied"
+	switch args[0] {
+	case "up":
+		applied, err = migrator.Up(ctx)
+	case "down":
+		applied, err = migrator.Down(ctx)
+		direction = "reverted"
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("to needs a version")
+		}
+		target, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		current, verErr := migrator.Version(ctx)
+		if verErr != nil {
+			return verErr
+		}
+		if target < current {
+			direction = "reverted"
+		}
+		applied, err = migrator.To(ctx, target)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrations(statuses)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	for _, migration := range applied {
+		fmt.Printf("%s %04d_%s\n", direction, migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("nothing to do")
+	}
+	return nil
+}
+
+func printMigrations(statuses []database.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
+
+func printDeadLetters(entries []dlq.Entry) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MESSAGE ID\tJOB ID\tTYPE\tSTATUS\tRECEIVES\tSENT\tERROR")
+	for _, e := range entries {
+		jobType, status, jobErr := "-", "-", ""
+		if e.Job != nil {
+			jobType, status, jobErr = e.Job.Type, string(e.Job.Status), e.Job.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			e.Message.MessageID, e.Message.JobID, jobType, status,
+			e.Message.ReceiveCount, e.Message.SentAt.Format(time.RFC3339), jobErr)
+	}
+	return w.Flush()
+}
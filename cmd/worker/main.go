@@ -6,11 +6,18 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/database"
-	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs/builtin"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/outbox"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue/backend"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/scheduler"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/webhook"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/worker"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/logger"
@@ -20,39 +27,112 @@ func main() {
 	cfg := config.Load()
 	slog := logger.New(cfg.LogLevel)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg, "jobs-worker")
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	sqlDB, _ := db.DB()
 	defer sqlDB.Close()
+	if err := metrics.RegisterDB(sqlDB, "jobsdb"); err != nil {
+		log.Fatalf("Failed to register database metrics: %v", err)
+	}
 
-	sqsClient, err := queue.NewSQSClient(cfg)
+	jobQueue, err := backend.New(cfg, db)
 	if err != nil {
-		log.Fatalf("Failed to create SQS client: %v", err)
+		log.Fatalf("Failed to create queue: %v", err)
 	}
 
-	// Create repository for scheduler
 	repo := repository.NewJobRepository(db)
-	
-	processor := worker.NewProcessor(db, sqsClient, slog)
-	scheduler := scheduler.New(repo, sqsClient, slog)
+	outboxRepo := repository.NewOutboxRepository(db)
+
+	if err := builtin.Register(jobs.Default, db, outboxRepo, slog); err != nil {
+		log.Fatalf("Failed to register job handlers: %v", err)
+	}
+
+	processor := worker.NewProcessor(repo, jobQueue, jobs.Default, slog, worker.Options{
+		WorkerID:        cfg.WorkerID,
+		Concurrency:     cfg.WorkerConcurrency,
+		BatchSize:       cfg.WorkerBatchSize,
+		TypeConcurrency: cfg.WorkerTypeConcurrency,
+
+		VisibilityTimeout:  cfg.QueueVisibilityTimeout,
+		HeartbeatInterval:  cfg.WorkerHeartbeatInterval,
+		MaxRuntime:         cfg.WorkerMaxJobRuntime,
+		CancelPollInterval: cfg.WorkerCancelPollInterval,
+	})
+	cronScheduler := scheduler.New(repository.NewScheduleRepository(db), slog, scheduler.Options{
+		SyncInterval: cfg.SchedulerSyncInterval,
+		Leases:       repository.NewLeaseRepository(db),
+		InstanceID:   cfg.WorkerID,
+		LeaseTTL:     cfg.SchedulerLeaseTTL,
+	})
+	promoter := scheduler.NewPromoter(repo, slog, cfg.SchedulerPromoteInterval)
+	relay := outbox.NewRelay(outboxRepo, jobQueue, slog, cfg.OutboxPollInterval, cfg.OutboxBatchSize)
+	dispatcher, err := webhook.NewDispatcher(repository.NewWebhookRepository(db), slog, webhook.Options{
+		Secret:      cfg.WebhookSecret,
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Timeout:     cfg.WebhookTimeout,
+		Interval:    cfg.WebhookPollInterval,
+	})
+	if err != nil {
+		slog.Warn("Webhook notifications are disabled", "error", err)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Start worker
+	workerDone := make(chan struct{})
 	go func() {
+		defer close(workerDone)
 		slog.Info("Starting worker...")
 		if err := processor.Start(ctx); err != nil {
 			slog.Error("Worker error", "error", err)
 		}
 	}()
 
+	// Start metrics listener
+	go func() {
+		if err := metrics.Serve(ctx, cfg.MetricsAddr, slog); err != nil {
+			slog.Error("Metrics listener error", "error", err)
+		}
+	}()
+
+	// Start outbox relay
+	go func() {
+		slog.Info("Starting outbox relay...")
+		if err := relay.Start(ctx); err != nil {
+			slog.Error("Outbox relay error", "error", err)
+		}
+	}()
+
+	// Start webhook dispatcher
+	if dispatcher != nil {
+		go func() {
+			slog.Info("Starting webhook dispatcher...")
+			if err := dispatcher.Start(ctx); err != nil {
+				slog.Error("Webhook dispatcher error", "error", err)
+			}
+		}()
+	}
+
+	// Start scheduled job promoter
+	go func() {
+		slog.Info("Starting scheduled job promoter...")
+		if err := promoter.Start(ctx); err != nil {
+			slog.Error("Promoter error", "error", err)
+		}
+	}()
+
 	// Start scheduler
 	go func() {
 		slog.Info("Starting scheduler...")
-		if err := scheduler.Start(ctx); err != nil {
+		if err := cronScheduler.Start(ctx); err != nil {
 			slog.Error("Scheduler error", "error", err)
 		}
 	}()
@@ -63,5 +143,12 @@ func main() {
 
 	slog.Info("Shutting down worker and scheduler...")
 	cancel()
+	<-workerDone
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
 	slog.Info("Worker exited")
 }
\ No newline at end of file
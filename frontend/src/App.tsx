@@ -1,5 +1,5 @@
 import { QueryClient, QueryClientProvider, useQuery, useMutation } from '@tanstack/react-query';
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import { jobsApi } from './services/api';
 import { CreateJobRequest } from './types/job';
 import './index.css';
@@ -8,11 +8,25 @@ import './App.css';
 const queryClient = new QueryClient({
   defaultOptions: {
     queries: {
-      refetchInterval: 2000,
+      // Job changes arrive over the event stream; this only catches up
+      // after it drops
+      refetchInterval: 30000,
     },
   },
 });
 
+// Job data is JSON; text that isn't valid JSON is sent as a JSON string
+const parseJobData = (text: string): unknown => {
+  try {
+    return JSON.parse(text);
+  } catch {
+    return text;
+  }
+};
+
+const formatJobData = (data: unknown) =>
+  typeof data === 'string' ? data : JSON.stringify(data);
+
 function JobDashboard() {
   const [statusFilter, setStatusFilter] = useState<string>('');
   const [jobType, setJobType] = useState('data-processing');
@@ -34,6 +48,15 @@ function JobDashboard() {
     },
   });
 
+  // Refetch the list whenever a job changes status
+  useEffect(() => {
+    const events = jobsApi.jobEvents();
+    events.addEventListener('status', () => {
+      queryClient.invalidateQueries({ queryKey: ['jobs'] });
+    });
+    return () => events.close();
+  }, []);
+
   const createJobMutation = useMutation({
     mutationFn: (data: CreateJobRequest) => jobsApi.createJob(data),
     onSuccess: () => {
@@ -42,11 +65,25 @@ function JobDashboard() {
     },
   });
 
+  const cancelJobMutation = useMutation({
+    mutationFn: (id: string) => jobsApi.cancelJob(id),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ['jobs'] });
+    },
+  });
+
+  const retryJobMutation = useMutation({
+    mutationFn: (id: string) => jobsApi.retryJob(id),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ['jobs'] });
+    },
+  });
+
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault();
     createJobMutation.mutate({
       type: jobType,
-      data: jobData,
+      data: parseJobData(jobData),
     });
   };
 
@@ -55,6 +92,9 @@ function JobDashboard() {
       case 'completed': return '#10b981';
       case 'processing': return '#3b82f6';
       case 'failed': return '#ef4444';
+      case 'retrying': return '#f59e0b';
+      case 'cancelled': return '#9ca3af';
+      case 'scheduled': return '#8b5cf6';
       default: return '#6b7280';
     }
   };
@@ -187,10 +227,13 @@ function JobDashboard() {
                 }}
               >
                 <option value="">All</option>
+                <option value="scheduled">Scheduled</option>
                 <option value="pending">Pending</option>
                 <option value="processing">Processing</option>
                 <option value="completed">Completed</option>
                 <option value="failed">Failed</option>
+                <option value="retrying">Retrying</option>
+                <option value="cancelled">Cancelled</option>
               </select>
             </div>
           </div>
@@ -241,7 +284,7 @@ function JobDashboard() {
                             {job.status}
                           </span>
                         </div>
-                        <p style={{ color: '#4b5563', marginBottom: '0.75rem' }}>{job.data}</p>
+                        <p style={{ color: '#4b5563', marginBottom: '0.75rem' }}>{formatJobData(job.data)}</p>
                         {job.result && (
                           <div style={{
                             display: 'flex',
@@ -279,6 +322,40 @@ function JobDashboard() {
                           {new Date(job.created_at).toLocaleString()}
                         </p>
                       </div>
+                      {['scheduled', 'pending', 'processing', 'retrying'].includes(job.status) && !job.cancel_requested_at && (
+                        <button
+                          onClick={() => cancelJobMutation.mutate(job.id)}
+                          disabled={cancelJobMutation.isPending}
+                          style={{
+                            padding: '0.375rem 0.75rem',
+                            border: '1px solid #e5e7eb',
+                            borderRadius: '6px',
+                            backgroundColor: 'white',
+                            color: '#4b5563',
+                            fontSize: '0.75rem',
+                            cursor: 'pointer',
+                          }}
+                        >
+                          Cancel
+                        </button>
+                      )}
+                      {['failed', 'cancelled'].includes(job.status) && (
+                        <button
+                          onClick={() => retryJobMutation.mutate(job.id)}
+                          disabled={retryJobMutation.isPending}
+                          style={{
+                            padding: '0.375rem 0.75rem',
+                            border: '1px solid #e5e7eb',
+                            borderRadius: '6px',
+                            backgroundColor: 'white',
+                            color: '#4b5563',
+                            fontSize: '0.75rem',
+                            cursor: 'pointer',
+                          }}
+                        >
+                          Retry
+                        </button>
+                      )}
                     </div>
                   </div>
                 ))
@@ -18,7 +18,25 @@ export const jobsApi = {
   
   getJob: (id: string) => 
     api.get<Job>(`/jobs/${id}`),
+
+  cancelJob: (id: string) =>
+    api.post<Job>(`/jobs/${id}/cancel`),
+
+  retryJob: (id: string, retriedBy?: string) =>
+    api.post<Job>(`/jobs/${id}/retry`, retriedBy ? { retried_by: retriedBy } : undefined),
   
+  // Responds 200 once the job finishes, or 202 with it unfinished after timeout
+  waitForJob: (id: string, timeout = '30s') =>
+    api.get<Job>(`/jobs/${id}/wait`, { params: { timeout } }),
+
   listJobs: (status?: string) => 
     api.get<JobListResponse>('/jobs', { params: { status } }),
+
+  // Server-Sent Events of job status changes; each is a 'status' event
+  // whose data is a JobEvent
+  jobEvents: (types?: string[]) =>
+    new EventSource(`${API_URL}/jobs/events${types?.length ? `?type=${encodeURIComponent(types.join(','))}` : ''}`),
+
+  jobEventsFor: (id: string) =>
+    new EventSource(`${API_URL}/jobs/${id}/events`),
 };
\ No newline at end of file
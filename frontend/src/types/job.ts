@@ -1,24 +1,56 @@
 export interface Job {
   id: string;
-  status: 'pending' | 'processing' | 'completed' | 'failed';
+  status: 'scheduled' | 'pending' | 'processing' | 'completed' | 'failed' | 'retrying' | 'cancelled';
   type: string;
-  data: string;
+  priority: 'high' | 'normal' | 'low';
+  data: unknown;
   result?: {
     processed_at: string;
     input_count: number;
     message: string;
   };
   error?: string;
+  attempts: number;
+  max_attempts: number;
+  attempt_history?: {
+    attempt: number;
+    started_at: string;
+    finished_at: string;
+    error?: string;
+  }[];
+  worker_id?: string;
+  lease_expires_at?: string;
+  cancel_requested_at?: string;
+  parent_id?: string;
+  retried_by?: string;
+  retried_at?: string;
+  run_at?: string;
+  callback_url?: string;
   created_at: string;
   updated_at: string;
 }
 
+export interface JobEvent {
+  job_id: string;
+  type: string;
+  status: Job['status'];
+  previous_status?: Job['status'];
+  attempts: number;
+  at: string;
+}
+
 export interface CreateJobRequest {
   type: string;
-  data: string;
+  data: unknown;
+  run_at?: string;
+  delay_seconds?: number;
+  priority?: 'high' | 'normal' | 'low';
+  callback_url?: string;
 }
 
 export interface JobListResponse {
   count: number;
   jobs: Job[];
+  next_cursor?: string;
+  total?: number;
 }
\ No newline at end of file
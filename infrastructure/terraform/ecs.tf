@@ -30,6 +30,10 @@ resource "aws_ecs_task_definition" "api" {
         name  = "SQS_QUEUE_URL"
         value = aws_sqs_queue.jobs.url
       },
+      {
+        name  = "SQS_DLQ_URL"
+        value = aws_sqs_queue.jobs_dlq.url
+      },
       {
         name  = "AWS_REGION"
         value = var.aws_region
@@ -41,6 +45,10 @@ resource "aws_ecs_task_definition" "api" {
       {
         name  = "LOG_LEVEL"
         value = "info"
+      },
+      {
+        name  = "WEBHOOK_SECRET"
+        value = var.webhook_secret
       }
     ]
 
@@ -85,6 +93,14 @@ resource "aws_ecs_task_definition" "worker" {
         name  = "SQS_QUEUE_URL"
         value = aws_sqs_queue.jobs.url
       },
+      {
+        name  = "SQS_HIGH_QUEUE_URL"
+        value = aws_sqs_queue.jobs_priority["high"].url
+      },
+      {
+        name  = "SQS_LOW_QUEUE_URL"
+        value = aws_sqs_queue.jobs_priority["low"].url
+      },
       {
         name  = "AWS_REGION"
         value = var.aws_region
@@ -108,6 +124,22 @@ resource "aws_ecs_task_definition" "worker" {
       {
         name  = "WORKER_POLL_INTERVAL"
         value = "5s"
+      },
+      {
+        name  = "QUEUE_VISIBILITY_TIMEOUT"
+        value = "${aws_sqs_queue.jobs.visibility_timeout_seconds}s"
+      },
+      {
+        name  = "WORKER_HEARTBEAT_INTERVAL"
+        value = "1m"
+      },
+      {
+        name  = "WORKER_MAX_JOB_RUNTIME"
+        value = "30m"
+      },
+      {
+        name  = "WEBHOOK_SECRET"
+        value = var.webhook_secret
       }
     ]
 
@@ -253,6 +253,26 @@ resource "aws_sqs_queue" "jobs" {
   }
 }
 
+# Priority queues, polled by the worker ahead of and behind the main queue.
+# They dead-letter into the same DLQ.
+resource "aws_sqs_queue" "jobs_priority" {
+  for_each = toset(["high", "low"])
+
+  name                       = "${local.name_suffix}-jobs-${each.key}-queue"
+  message_retention_seconds  = 345600  # 4 days
+  visibility_timeout_seconds = 300     # 5 minutes
+  receive_wait_time_seconds  = 20      # Long polling
+
+  redrive_policy = jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.jobs_dlq.arn
+    maxReceiveCount     = 3
+  })
+
+  tags = {
+    Name = "${local.name_suffix}-jobs-${each.key}-queue"
+  }
+}
+
 # Dead Letter Queue
 resource "aws_sqs_queue" "jobs_dlq" {
   name                      = "${local.name_suffix}-jobs-dlq"
@@ -501,11 +521,14 @@ resource "aws_iam_role_policy" "worker_sqs_access" {
       Action = [
         "sqs:ReceiveMessage",
         "sqs:DeleteMessage",
+        "sqs:ChangeMessageVisibility",
         "sqs:GetQueueAttributes",
         "sqs:SendMessage"
       ]
       Resource = [
         aws_sqs_queue.jobs.arn,
+        aws_sqs_queue.jobs_priority["high"].arn,
+        aws_sqs_queue.jobs_priority["low"].arn,
         aws_sqs_queue.jobs_dlq.arn
       ]
     }]
@@ -54,6 +54,13 @@ variable "rds_password" {
   # No default - must be provided at runtime
 }
 
+variable "webhook_secret" {
+  description = "Key the worker signs webhook notifications with - provide via tfvars or TF_VAR_webhook_secret; without one jobs can't have a callback_url"
+  type        = string
+  sensitive   = true
+  default     = ""
+}
+
 variable "ecs_task_cpu" {
   description = "ECS task CPU units"
   type        = string
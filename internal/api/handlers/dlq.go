@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/api/middleware"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+)
+
+const (
+	defaultDLQLimit = 10
+	maxDLQLimit     = 100
+)
+
+type redrivePayload struct {
+	MessageIDs []string `json:"message_ids" validate:"required,min=1,max=100"`
+}
+
+func (h *Handler) ListDeadLetters(c *gin.Context) {
+	if h.dlq == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": queue.ErrNoDeadLetterQueue.Error()})
+		return
+	}
+
+	limit := defaultDLQLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxDLQLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.dlq.List(c.Request.Context(), limit)
+	if err != nil {
+		h.deadLetterError(c, "failed to list dead letters", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": entries,
+		"count":    len(entries),
+	})
+}
+
+func (h *Handler) RedriveDeadLetters(c *gin.Context) {
+	if h.dlq == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": queue.ErrNoDeadLetterQueue.Error()})
+		return
+	}
+
+	var payload redrivePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		middleware.ValidationError(c, err)
+		return
+	}
+	if err := middleware.ValidateStruct(payload); err != nil {
+		middleware.ValidationError(c, err)
+		return
+	}
+
+	moved, err := h.dlq.Redrive(c.Request.Context(), payload.MessageIDs)
+	if err != nil {
+		h.logger.Error("failed to redrive dead letters", "error", err, "redriven", len(moved))
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":    "redrive incomplete",
+			"redriven": moved,
+			"count":    len(moved),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redriven": moved,
+		"count":    len(moved),
+	})
+}
+
+func (h *Handler) deadLetterError(c *gin.Context, msg string, err error) {
+	if errors.Is(err, queue.ErrNoDeadLetterQueue) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error(msg, "error", err)
+	c.JSON(http.StatusBadGateway, gin.H{"error": msg})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/events"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+// streamHeartbeat keeps idle streams from being cut by the load balancer,
+// whose idle timeout is 60s
+const streamHeartbeat = 15 * time.Second
+
+// StreamJob streams a job's status changes as Server-Sent Events, starting
+// with its current status, and ends once the job finishes
+func (h *Handler) StreamJob(c *gin.Context) {
+	if !h.eventsAvailable(c) {
+		return
+	}
+
+	id := c.Param("id")
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID"})
+		return
+	}
+
+	// Subscribe before reading the job so no change falls in between
+	sub := h.broker.Subscribe(func(event models.JobEvent) bool {
+		return event.JobID == jobID
+	})
+	defer sub.Close()
+
+	job, err := h.repoFor(c).GetJob(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		default:
+			h.logger.Error("failed to get job", "error", err, "job_id", id)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job"})
+		}
+		return
+	}
+
+	current := models.NewJobEvent(job)
+	startStream(c)
+	writeEvent(c, current)
+	if current.Final() {
+		return
+	}
+
+	stream(c, sub, func(event models.JobEvent) bool {
+		// Changes already reflected in the job read above
+		if !event.At.After(current.At) {
+			return true
+		}
+		writeEvent(c, event)
+		return !event.Final()
+	})
+}
+
+// StreamJobs streams the status changes of all jobs, or of those whose type
+// is given in type, as Server-Sent Events
+func (h *Handler) StreamJobs(c *gin.Context) {
+	if !h.eventsAvailable(c) {
+		return
+	}
+
+	types := queryList(c, "type")
+	sub := h.broker.Subscribe(func(event models.JobEvent) bool {
+		return len(types) == 0 || slices.Contains(types, event.Type)
+	})
+	defer sub.Close()
+
+	startStream(c)
+	c.Writer.Flush()
+	stream(c, sub, func(event models.JobEvent) bool {
+		writeEvent(c, event)
+		return true
+	})
+}
+
+// stream passes sub's events to write until it returns false, the client
+// goes away or the subscription is closed, sending heartbeats meanwhile. A
+// closed subscription ends the response, and the client's EventSource
+// reconnects.
+func stream(c *gin.Context, sub *events.Subscription, write func(models.JobEvent) bool) {
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok || !write(event) {
+				return
+			}
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+func startStream(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Stop proxies such as nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+func writeEvent(c *gin.Context, event models.JobEvent) {
+	c.SSEvent("status", event)
+	c.Writer.Flush()
+}
+
+func (h *Handler) eventsAvailable(c *gin.Context) bool {
+	if h.broker == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "job events are not enabled"})
+		return false
+	}
+	return true
+}
@@ -1,32 +1,102 @@
 package handlers
 
 import (
-	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/api/middleware"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/dlq"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/events"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
 )
 
 type Handler struct {
-	repo   interfaces.Repository
-	queue  interfaces.Queue
-	logger *slog.Logger
+	repo      interfaces.Repository
+	schedules interfaces.ScheduleRepository
+	leases    interfaces.LeaseRepository
+	webhooks  interfaces.WebhookRepository
+	queue     interfaces.Queue
+	dlq       *dlq.Service
+	broker    *events.Broker
+	registry  *jobs.Registry
+	logger    *slog.Logger
+	// callbacks is whether jobs may have a callback_url
+	callbacks bool
 }
 
 func New(db *gorm.DB, queue interfaces.Queue, logger *slog.Logger) *Handler {
-	return &Handler{
-		repo:   repository.NewJobRepository(db),
-		queue:  queue,
-		logger: logger,
+	h := NewWithRepository(repository.NewJobRepository(db), queue, logger)
+	h.schedules = repository.NewScheduleRepository(db)
+	h.leases = repository.NewLeaseRepository(db)
+	h.webhooks = repository.NewWebhookRepository(db)
+	return h
+}
+
+// NewWithRepository creates a handler on any repository implementation,
+// such as repository.MemoryRepository in tests
+func NewWithRepository(repo interfaces.Repository, queue interfaces.Queue, logger *slog.Logger) *Handler {
+	h := &Handler{
+		repo:     repo,
+		queue:    queue,
+		registry: jobs.Default,
+		logger:   logger,
+	}
+	if dlqQueue, ok := queue.(interfaces.DeadLetterQueue); ok {
+		h.dlq = dlq.NewService(dlqQueue, h.repo, logger)
+	}
+	if schedules, ok := repo.(interfaces.ScheduleRepository); ok {
+		h.schedules = schedules
 	}
+	if leases, ok := repo.(interfaces.LeaseRepository); ok {
+		h.leases = leases
+	}
+	if webhooks, ok := repo.(interfaces.WebhookRepository); ok {
+		h.webhooks = webhooks
+	}
+	return h
+}
+
+// WithRegistry makes CreateJob validate job data against the schemas in
+// registry instead of jobs.Default
+func (h *Handler) WithRegistry(registry *jobs.Registry) *Handler {
+	h.registry = registry
+	return h
+}
+
+// WithEvents streams the job status changes published on broker; without
+// it the event streams respond 501
+func (h *Handler) WithEvents(broker *events.Broker) *Handler {
+	h.broker = broker
+	return h
+}
+
+// WithCallbacks accepts a callback_url on new jobs, for when the worker has a
+// WEBHOOK_SECRET to sign their notifications with; without it jobs giving
+// one are rejected
+func (h *Handler) WithCallbacks() *Handler {
+	h.callbacks = true
+	return h
+}
+
+// repoFor returns the job repository running its queries with the
+// request's context, so they are traced as part of the request
+func (h *Handler) repoFor(c *gin.Context) interfaces.Repository {
+	return repository.WithContext(c.Request.Context(), h.repo)
 }
 
 func (h *Handler) Health(c *gin.Context) {
@@ -36,7 +106,18 @@ func (h *Handler) Health(c *gin.Context) {
 	})
 }
 
+// CreateJob creates a job and responds 201. With wait=true it responds once
+// the job finishes, or with 202 once timeout passes, as WaitJob does.
 func (h *Handler) CreateJob(c *gin.Context) {
+	wait, timeout, err := parseWaitOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if wait && !h.eventsAvailable(c) {
+		return
+	}
+
 	var payload models.JobPayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		middleware.ValidationError(c, err)
@@ -49,33 +130,103 @@ func (h *Handler) CreateJob(c *gin.Context) {
 		return
 	}
 
+	// Then the data against the schema declared for its type
+	if err := h.registry.Validate(payload.Type, payload.Data); err != nil {
+		middleware.ValidationError(c, err)
+		return
+	}
+
+	if payload.CallbackURL != "" && !h.callbacks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url is not available: WEBHOOK_SECRET is not configured"})
+		return
+	}
+
+	now := time.Now()
+	runAt, err := jobRunAt(payload, now)
+	if err != nil {
+		middleware.ValidationError(c, err)
+		return
+	}
+
+	maxAttempts := payload.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = models.DefaultMaxAttempts
+	}
+
 	job := &models.Job{
-		Status: models.JobStatusPending,
-		Type:   payload.Type,
-		Data:   payload.Data,
+		Status:      models.JobStatusPending,
+		Type:        payload.Type,
+		Data:        payload.Data,
+		MaxAttempts: maxAttempts,
+		Priority:    payload.Priority,
+		CallbackURL: payload.CallbackURL,
+		// The worker continues this request's trace
+		TraceContext: tracing.Inject(c.Request.Context()),
+	}
+	if runAt != nil {
+		job.RunAt = runAt
+		// Beyond the queue's longest delay the job waits in the database
+		// until promoted
+		if runAt.Sub(now) > queue.MaxDelay {
+			job.Status = models.JobStatusScheduled
+		}
 	}
 
-	if err := h.repo.CreateJob(job); err != nil {
+	if err := h.repoFor(c).CreateJob(job); err != nil {
 		h.logger.Error("failed to create job", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create job"})
 		return
 	}
+	metrics.JobsCreated.WithLabelValues(job.Type).Inc()
 
-	// Queue job asynchronously with background context
-	go func() {
-		ctx := context.Background()
-		if err := h.queue.SendMessage(ctx, job.ID.String()); err != nil {
-			h.logger.Error("failed to queue job", "error", err, "job_id", job.ID)
+	// The job is queued by the outbox relay from the row written with it
+	if wait {
+		finished, err := h.awaitJob(c, job.ID, timeout)
+		switch {
+		case c.Request.Context().Err() != nil:
+			return
+		case err != nil:
+			// The job was created all the same, so report it as it was
+			h.logger.Error("failed to wait for job", "error", err, "job_id", job.ID)
+		default:
+			job = finished
 		}
-	}()
-
+		if !job.IsFinished() {
+			c.JSON(http.StatusAccepted, job)
+			return
+		}
+	}
 	c.JSON(http.StatusCreated, job)
 }
 
+// jobRunAt resolves when a new job is due from its run_at or delay_seconds,
+// nil meaning now
+func jobRunAt(payload models.JobPayload, now time.Time) (*time.Time, error) {
+	var runAt time.Time
+	switch {
+	case payload.RunAt != nil && payload.DelaySeconds != 0:
+		return nil, middleware.FieldErrors{"run_at": "run_at and delay_seconds can't both be set"}
+	case payload.RunAt != nil:
+		runAt = *payload.RunAt
+	case payload.DelaySeconds != 0:
+		runAt = now.Add(time.Duration(payload.DelaySeconds) * time.Second)
+	default:
+		return nil, nil
+	}
+
+	if runAt.Sub(now) > models.MaxJobDelay {
+		return nil, middleware.FieldErrors{"run_at": "run_at must be within 365 days"}
+	}
+	if !runAt.After(now) {
+		return nil, nil
+	}
+	return &runAt, nil
+}
+
 func (h *Handler) GetJob(c *gin.Context) {
 	id := c.Param("id")
-	
-	job, err := h.repo.GetJob(id)
+
+	job, err := h.repoFor(c).GetJob(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrInvalidID):
@@ -92,18 +243,173 @@ func (h *Handler) GetJob(c *gin.Context) {
 	c.JSON(http.StatusOK, job)
 }
 
+// CancelJob cancels a job that hasn't started, responding 200. A running job
+// is asked to stop and the response is 202; its worker marks it cancelled
+// once the handler returns.
+func (h *Handler) CancelJob(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.repoFor(c).CancelJob(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidID):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID"})
+		case errors.Is(err, repository.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		case errors.Is(err, repository.ErrJobFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": "job already " + string(job.Status)})
+		default:
+			h.logger.Error("failed to cancel job", "error", err, "job_id", id)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel job"})
+		}
+		return
+	}
+
+	if job.Status != models.JobStatusCancelled {
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryJob re-runs a failed or cancelled job as a new job whose parent_id is
+// the original. The optional retried_by body field records who asked for
+// it, defaulting to the client's address.
+func (h *Handler) RetryJob(c *gin.Context) {
+	id := c.Param("id")
+
+	var payload models.RetryPayload
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			middleware.ValidationError(c, err)
+			return
+		}
+		if err := middleware.ValidateStruct(payload); err != nil {
+			middleware.ValidationError(c, err)
+			return
+		}
+	}
+	if payload.RetriedBy == "" {
+		payload.RetriedBy = c.ClientIP()
+	}
+
+	job, err := h.repoFor(c).RetryJob(id, payload.RetriedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidID):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID"})
+		case errors.Is(err, repository.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		case errors.Is(err, repository.ErrJobNotRerunnable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("failed to retry job", "error", err, "job_id", id)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retry job"})
+		}
+		return
+	}
+
+	// Queued by the outbox relay, like a newly created job
+	metrics.JobsCreated.WithLabelValues(job.Type).Inc()
+	h.logger.Info("job retried", "job_id", job.ID, "parent_id", id, "retried_by", job.RetriedBy)
+	c.JSON(http.StatusCreated, job)
+}
+
+// ListJobs returns a page of jobs. The status and type parameters may be
+// repeated or comma-separated; next_cursor is passed back as cursor to get
+// the following page.
 func (h *Handler) ListJobs(c *gin.Context) {
-	status := c.Query("status")
-	
-	jobs, err := h.repo.ListJobs(status, 100)
+	filter, err := parseJobFilter(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.repoFor(c).FindJobs(filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
 		h.logger.Error("failed to list jobs", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list jobs"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"jobs":  jobs,
-		"count": len(jobs),
-	})
-}
\ No newline at end of file
+	response := gin.H{
+		"jobs":  page.Jobs,
+		"count": len(page.Jobs),
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	if page.Total != nil {
+		response["total"] = *page.Total
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func parseJobFilter(c *gin.Context) (models.JobFilter, error) {
+	filter := models.JobFilter{
+		Types:  queryList(c, "type"),
+		Search: c.Query("q"),
+		Cursor: c.Query("cursor"),
+	}
+	for _, value := range queryList(c, "status") {
+		status := models.JobStatus(value)
+		if !slices.Contains(models.JobStatuses, status) {
+			return filter, fmt.Errorf("unknown status %q", value)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	for name, dst := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*dst = &t
+		}
+	}
+
+	switch order := models.JobOrder(c.Query("order")); order {
+	case "", models.JobOrderNewest, models.JobOrderOldest:
+		filter.Order = order
+	default:
+		return filter, errors.New("order must be asc or desc")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > models.MaxJobListLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", models.MaxJobListLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("include_total"); value != "" {
+		includeTotal, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("include_total must be true or false")
+		}
+		filter.IncludeTotal = includeTotal
+	}
+	return filter, nil
+}
+
+// queryList collects a query parameter given repeatedly or comma-separated
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, param := range c.QueryArray(name) {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
@@ -1,22 +1,35 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/api/middleware"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/events"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing/tracingtest"
 )
 
 // Mock Repository
@@ -53,6 +66,14 @@ func (m *mockRepository) ListJobs(status string, limit int) ([]models.Job, error
 	return nil, args.Error(1)
 }
 
+func (m *mockRepository) FindJobs(filter models.JobFilter) (*models.JobPage, error) {
+	args := m.Called(filter)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.JobPage), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *mockRepository) GetPendingJobs(limit int) ([]models.Job, error) {
 	args := m.Called(limit)
 	if args.Get(0) != nil {
@@ -61,26 +82,75 @@ func (m *mockRepository) GetPendingJobs(limit int) ([]models.Job, error) {
 	return nil, args.Error(1)
 }
 
+func (m *mockRepository) ClaimJob(id string, workerID string, lease time.Duration) (*models.Job, error) {
+	args := m.Called(id, workerID, lease)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Job), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) RenewJobLease(id string, workerID string, lease time.Duration) error {
+	args := m.Called(id, workerID, lease)
+	return args.Error(0)
+}
+
+func (m *mockRepository) ResetJobForRedrive(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockRepository) FinishJob(job *models.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *mockRepository) CancelJob(id string) (*models.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Job), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockRepository) RetryJob(id string, retriedBy string) (*models.Job, error) {
+	args := m.Called(id, retriedBy)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Job), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 // Mock Queue
 type mockQueue struct {
 	mock.Mock
 }
 
-func (m *mockQueue) SendMessage(ctx context.Context, jobID string) error {
+func (m *mockQueue) SendMessage(ctx context.Context, jobID string, opts ...queue.SendOption) error {
 	args := m.Called(ctx, jobID)
 	return args.Error(0)
 }
 
-func (m *mockQueue) ReceiveMessages(ctx context.Context) ([]types.Message, error) {
-	args := m.Called(ctx)
+func (m *mockQueue) ReceiveMessages(ctx context.Context, maxMessages int) ([]queue.Message, error) {
+	args := m.Called(ctx, maxMessages)
 	if args.Get(0) != nil {
-		return args.Get(0).([]types.Message), args.Error(1)
+		return args.Get(0).([]queue.Message), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *mockQueue) DeleteMessage(ctx context.Context, receiptHandle string) error {
-	args := m.Called(ctx, receiptHandle)
+func (m *mockQueue) Ack(ctx context.Context, msg queue.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func (m *mockQueue) Nack(ctx context.Context, msg queue.Message, delay time.Duration) error {
+	args := m.Called(ctx, msg, delay)
+	return args.Error(0)
+}
+
+func (m *mockQueue) Extend(ctx context.Context, msg queue.Message, timeout time.Duration) error {
+	args := m.Called(ctx, msg, timeout)
 	return args.Error(0)
 }
 
@@ -116,10 +186,6 @@ func (h *handlerWithMocks) CreateJob(c *gin.Context) {
 		return
 	}
 
-	go func() {
-		h.queue.SendMessage(context.Background(), job.ID.String())
-	}()
-
 	c.JSON(http.StatusCreated, job)
 }
 
@@ -178,6 +244,58 @@ func TestHealth(t *testing.T) {
 	assert.Equal(t, "api", response["service"])
 }
 
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.Metrics())
+	NewWithRepository(repository.NewMemoryRepository(), &mockQueue{}, slog.Default()).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/jobs", bytes.NewBufferString(`{"type": "metered", "data": {}}`))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/jobs/"+uuid.New().String(), nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `jobs_created_total{type="metered"} 1`)
+	// Requests are recorded by route, not by path
+	assert.Contains(t, w.Body.String(), `http_request_duration_seconds_count{method="GET",route="/api/jobs/:id",status="404"}`)
+}
+
+func TestCreateJob_TraceContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spans := tracingtest.Record(t)
+
+	repo := repository.NewMemoryRepository()
+	router := gin.New()
+	router.Use(otelgin.Middleware("jobs-api"))
+	NewWithRepository(repo, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/jobs", bytes.NewBufferString(`{"type": "traced", "data": {}}`))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	request := tracingtest.Find(spans, "/api/jobs")
+	require.NotNil(t, request)
+
+	// The outbox message carries the request's trace on to the queue
+	messages, err := repo.ClaimOutboxMessages(10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	carried := trace.SpanContextFromContext(tracing.Extract(context.Background(), messages[0].TraceContext))
+	assert.Equal(t, request.SpanContext.TraceID(), carried.TraceID())
+	assert.Equal(t, request.SpanContext.SpanID(), carried.SpanID())
+}
+
 func TestCreateJob_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -191,14 +309,13 @@ func TestCreateJob_Success(t *testing.T) {
 	}
 
 	mockRepo.On("CreateJob", mock.Anything).Return(nil)
-	mockSQS.On("SendMessage", mock.Anything, mock.Anything).Return(nil)
 
 	router := gin.New()
 	router.POST("/jobs", h.CreateJob)
 
 	payload := models.JobPayload{
 		Type: "data-processing",
-		Data: "test data",
+		Data: models.JSONString("test data"),
 	}
 	body, _ := json.Marshal(payload)
 
@@ -217,6 +334,7 @@ func TestCreateJob_Success(t *testing.T) {
 	assert.Equal(t, "data-processing", job.Type)
 
 	mockRepo.AssertExpectations(t)
+	mockSQS.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
 }
 
 func TestCreateJob_InvalidJSON(t *testing.T) {
@@ -242,6 +360,167 @@ func TestCreateJob_InvalidJSON(t *testing.T) {
 	assert.Equal(t, "invalid request body", response["error"])
 }
 
+func TestCreateJob_Schema(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.RegisterSchema("email", []byte(`{
+		"type": "object",
+		"required": ["to"],
+		"properties": {"to": {"type": "string"}, "cc": {"type": "array", "items": {"type": "string"}}}
+	}`)))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFields map[string]string
+	}{
+		{
+			name:       "matches schema",
+			body:       `{"type": "email", "data": {"to": "a@example.com"}}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "type without schema",
+			body:       `{"type": "report", "data": [1, 2, 3]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "schema violations",
+			body:       `{"type": "email", "data": {"cc": ["b@example.com", 7]}}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: map[string]string{
+				"data":      "missing property 'to'",
+				"data.cc.1": "got number, want string",
+			},
+		},
+		{
+			name:       "null data",
+			body:       `{"type": "report", "data": null}`,
+			wantStatus: http.StatusBadRequest,
+			wantFields: map[string]string{"data": "data is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockRepository{}
+			if tt.wantStatus == http.StatusCreated {
+				mockRepo.On("CreateJob", mock.Anything).Return(nil)
+			}
+
+			router := gin.New()
+			NewWithRepository(mockRepo, &mockQueue{}, slog.Default()).WithRegistry(registry).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/jobs", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantFields != nil {
+				var response struct {
+					Fields map[string]string `json:"fields"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.wantFields, response.Fields)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateJob_RunAt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	soon := time.Now().Add(5 * time.Minute).UTC().Format(time.RFC3339)
+	later := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	tooFar := time.Now().Add(400 * 24 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   int
+		wantStatus models.JobStatus
+		wantRunAt  bool
+		wantFields map[string]string
+	}{
+		{
+			name:       "delay within the queue's limit",
+			body:       `{"type": "cleanup", "data": {}, "delay_seconds": 60}`,
+			wantCode:   http.StatusCreated,
+			wantStatus: models.JobStatusPending,
+			wantRunAt:  true,
+		},
+		{
+			name:       "run_at within the queue's limit",
+			body:       `{"type": "cleanup", "data": {}, "run_at": "` + soon + `"}`,
+			wantCode:   http.StatusCreated,
+			wantStatus: models.JobStatusPending,
+			wantRunAt:  true,
+		},
+		{
+			name:       "run_at beyond the queue's limit",
+			body:       `{"type": "cleanup", "data": {}, "run_at": "` + later + `"}`,
+			wantCode:   http.StatusCreated,
+			wantStatus: models.JobStatusScheduled,
+			wantRunAt:  true,
+		},
+		{
+			name:       "run_at in the past runs now",
+			body:       `{"type": "cleanup", "data": {}, "run_at": "` + past + `"}`,
+			wantCode:   http.StatusCreated,
+			wantStatus: models.JobStatusPending,
+		},
+		{
+			name:       "both set",
+			body:       `{"type": "cleanup", "data": {}, "run_at": "` + soon + `", "delay_seconds": 60}`,
+			wantCode:   http.StatusBadRequest,
+			wantFields: map[string]string{"run_at": "run_at and delay_seconds can't both be set"},
+		},
+		{
+			name:       "too far out",
+			body:       `{"type": "cleanup", "data": {}, "run_at": "` + tooFar + `"}`,
+			wantCode:   http.StatusBadRequest,
+			wantFields: map[string]string{"run_at": "run_at must be within 365 days"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			router := gin.New()
+			NewWithRepository(repo, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/jobs", bytes.NewBufferString(tt.body))
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			if tt.wantFields != nil {
+				var response struct {
+					Fields map[string]string `json:"fields"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.wantFields, response.Fields)
+				return
+			}
+
+			var job models.Job
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+			assert.Equal(t, tt.wantStatus, job.Status)
+			assert.Equal(t, tt.wantRunAt, job.RunAt != nil)
+
+			// Only jobs within the queue's limit are handed to the relay
+			count, err := repo.CountUndeliveredOutbox()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus == models.JobStatusPending, count == 1)
+		})
+	}
+}
+
 func TestGetJob_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -257,7 +536,7 @@ func TestGetJob_Success(t *testing.T) {
 		ID:     jobID,
 		Status: models.JobStatusCompleted,
 		Type:   "test",
-		Data:   "test data",
+		Data:   models.JSONString("test data"),
 	}
 
 	mockRepo.On("GetJob", jobID.String()).Return(expectedJob, nil)
@@ -337,8 +616,8 @@ func TestListJobs_Success(t *testing.T) {
 	}
 
 	expectedJobs := []models.Job{
-		{ID: uuid.New(), Status: models.JobStatusPending, Type: "test1", Data: "data1"},
-		{ID: uuid.New(), Status: models.JobStatusCompleted, Type: "test2", Data: "data2"},
+		{ID: uuid.New(), Status: models.JobStatusPending, Type: "test1", Data: models.JSONString("data1")},
+		{ID: uuid.New(), Status: models.JobStatusCompleted, Type: "test2", Data: models.JSONString("data2")},
 	}
 
 	mockRepo.On("ListJobs", "", 100).Return(expectedJobs, nil)
@@ -371,7 +650,7 @@ func TestListJobs_WithStatusFilter(t *testing.T) {
 	}
 
 	expectedJobs := []models.Job{
-		{ID: uuid.New(), Status: models.JobStatusPending, Type: "test1", Data: "data1"},
+		{ID: uuid.New(), Status: models.JobStatusPending, Type: "test1", Data: models.JSONString("data1")},
 	}
 
 	mockRepo.On("ListJobs", "pending", 100).Return(expectedJobs, nil)
@@ -392,3 +671,758 @@ func TestListJobs_WithStatusFilter(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestListJobs_Filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	after := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	total := int64(7)
+
+	tests := []struct {
+		name       string
+		query      string
+		filter     models.JobFilter
+		page       *models.JobPage
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "defaults",
+			filter:     models.JobFilter{},
+			page:       &models.JobPage{Jobs: []models.Job{}},
+			wantStatus: http.StatusOK,
+			wantBody:   `"count":0`,
+		},
+		{
+			name:  "all filters",
+			query: "status=failed,cancelled&type=cleanup&type=report&created_after=2024-01-02T03:04:05Z&q=timeout&order=asc&limit=2&cursor=abc&include_total=true",
+			filter: models.JobFilter{
+				Statuses:     []models.JobStatus{models.JobStatusFailed, models.JobStatusCancelled},
+				Types:        []string{"cleanup", "report"},
+				CreatedAfter: &after,
+				Search:       "timeout",
+				Order:        models.JobOrderOldest,
+				Limit:        2,
+				Cursor:       "abc",
+				IncludeTotal: true,
+			},
+			page:       &models.JobPage{Jobs: []models.Job{}, NextCursor: "next", Total: &total},
+			wantStatus: http.StatusOK,
+			wantBody:   `"next_cursor":"next","total":7`,
+		},
+		{
+			name:       "invalid cursor",
+			query:      "cursor=abc",
+			filter:     models.JobFilter{Cursor: "abc"},
+			err:        repository.ErrInvalidCursor,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid cursor",
+		},
+		{
+			name:       "unknown status",
+			query:      "status=failed,done",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `unknown status \"done\"`,
+		},
+		{
+			name:       "invalid limit",
+			query:      "limit=0",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "limit must be between 1 and 1000",
+		},
+		{
+			name:       "invalid order",
+			query:      "order=sideways",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid date",
+			query:      "created_before=yesterday",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "created_before must be an RFC 3339 timestamp",
+		},
+		{
+			name:       "database error",
+			filter:     models.JobFilter{},
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockRepository{}
+			if tt.page != nil || tt.err != nil {
+				mockRepo.On("FindJobs", tt.filter).Return(tt.page, tt.err)
+			}
+
+			router := gin.New()
+			NewWithRepository(mockRepo, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/jobs?"+tt.query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		job        *models.Job
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "not started",
+			job:        &models.Job{ID: id, Status: models.JobStatusCancelled},
+			wantStatus: http.StatusOK,
+			wantBody:   `"status":"cancelled"`,
+		},
+		{
+			name:       "running",
+			job:        &models.Job{ID: id, Status: models.JobStatusProcessing, CancelRequestedAt: &now},
+			wantStatus: http.StatusAccepted,
+			wantBody:   `"cancel_requested_at"`,
+		},
+		{
+			name:       "finished",
+			job:        &models.Job{ID: id, Status: models.JobStatusCompleted},
+			err:        repository.ErrJobFinished,
+			wantStatus: http.StatusConflict,
+			wantBody:   "job already completed",
+		},
+		{
+			name:       "not found",
+			err:        repository.ErrJobNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid ID",
+			err:        repository.ErrInvalidID,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "database error",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockRepository{}
+			mockRepo.On("CancelJob", id.String()).Return(tt.job, tt.err)
+
+			router := gin.New()
+			NewWithRepository(mockRepo, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/jobs/"+id.String()+"/cancel", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRetryJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	id := uuid.New()
+	now := time.Now()
+	rerun := &models.Job{ID: uuid.New(), Status: models.JobStatusPending, ParentID: &id, RetriedBy: "alice", RetriedAt: &now}
+
+	tests := []struct {
+		name       string
+		body       string
+		retriedBy  string
+		job        *models.Job
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "failed job",
+			body:       `{"retried_by":"alice"}`,
+			retriedBy:  "alice",
+			job:        rerun,
+			wantStatus: http.StatusCreated,
+			wantBody:   `"parent_id":"` + id.String() + `"`,
+		},
+		{
+			name:       "no body records the client",
+			retriedBy:  "192.0.2.1",
+			job:        rerun,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "not failed or cancelled",
+			body:       `{"retried_by":"alice"}`,
+			retriedBy:  "alice",
+			err:        repository.ErrJobNotRerunnable,
+			wantStatus: http.StatusConflict,
+			wantBody:   "only failed or cancelled jobs can be retried",
+		},
+		{
+			name:       "not found",
+			body:       `{"retried_by":"alice"}`,
+			retriedBy:  "alice",
+			err:        repository.ErrJobNotFound,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid body",
+			body:       `{"retried_by":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "database error",
+			body:       `{"retried_by":"alice"}`,
+			retriedBy:  "alice",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockRepository{}
+			if tt.retriedBy != "" {
+				mockRepo.On("RetryJob", id.String(), tt.retriedBy).Return(tt.job, tt.err)
+			}
+
+			router := gin.New()
+			NewWithRepository(mockRepo, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/jobs/"+id.String()+"/retry", bytes.NewBufferString(tt.body))
+			req.RemoteAddr = "192.0.2.1:1234"
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.RegisterSchema("report", []byte(`{"type": "object", "required": ["at"]}`)))
+
+	router := gin.New()
+	NewWithRepository(repository.NewMemoryRepository(), &mockQueue{}, slog.Default()).
+		WithRegistry(registry).
+		RegisterRoutes(router)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/schedules", `{
+		"name": "nightly-report",
+		"cron": "0 2 * * *",
+		"job_type": "report",
+		"payload_template": "{\"at\": \"{{.ScheduledAt.Format \"2006-01-02\"}}\"}",
+		"timezone": "Europe/Madrid"
+	}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.Schedule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, created.Enabled)
+	assert.Equal(t, "Europe/Madrid", created.Timezone)
+	assert.Equal(t, models.MisfireSkip, created.MisfirePolicy)
+	assert.Equal(t, 86400, created.CatchUpWindowSeconds)
+
+	// Names are unique
+	w = do("POST", "/api/schedules", `{"name": "nightly-report", "cron": "@daily", "job_type": "other", "payload_template": "1"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = do("POST", "/api/schedules/"+created.ID.String()+"/pause", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"enabled":false`)
+
+	// Replacing without enabled keeps it paused
+	w = do("PUT", "/api/schedules/"+created.ID.String(), `{
+		"name": "nightly-report",
+		"cron": "0 3 * * *",
+		"job_type": "report",
+		"payload_template": "{\"at\": \"now\"}"
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"cron":"0 3 * * *"`)
+	assert.Contains(t, w.Body.String(), `"enabled":false`)
+	assert.Contains(t, w.Body.String(), `"timezone":"UTC"`)
+
+	w = do("POST", "/api/schedules/"+created.ID.String()+"/resume", "")
+	assert.Contains(t, w.Body.String(), `"enabled":true`)
+
+	w = do("PUT", "/api/schedules/"+created.ID.String(), `{
+		"name": "nightly-report",
+		"cron": "0 3 * * *",
+		"job_type": "report",
+		"payload_template": "{\"at\": \"now\"}",
+		"misfire_policy": "run_all",
+		"catch_up_window_seconds": 3600
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"misfire_policy":"run_all","catch_up_window_seconds":3600`)
+	w = do("PUT", "/api/schedules/"+created.ID.String(), `{
+		"name": "nightly-report",
+		"cron": "0 3 * * *",
+		"job_type": "report",
+		"payload_template": "{\"at\": \"now\"}",
+		"misfire_policy": "sometimes"
+	}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do("GET", "/api/schedules/"+created.ID.String()+"/runs", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":0`)
+	w = do("GET", "/api/schedules/"+created.ID.String()+"/runs?limit=500", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do("GET", "/api/schedules/"+uuid.New().String()+"/runs", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do("GET", "/api/schedules", "")
+	assert.Contains(t, w.Body.String(), `"count":1`)
+
+	w = do("DELETE", "/api/schedules/"+created.ID.String(), "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do("GET", "/api/schedules/"+created.ID.String(), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = do("GET", "/api/schedules/not-a-uuid", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateSchedule_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.RegisterSchema("report", []byte(`{"type": "object", "required": ["at"]}`)))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantFields map[string]string
+	}{
+		{
+			name: "bad cron",
+			body: `{"name": "a", "cron": "every day", "job_type": "x", "payload_template": "1"}`,
+			wantFields: map[string]string{
+				"cron": "invalid cron expression: expected 5 to 6 fields, found 2: [every day]",
+			},
+		},
+		{
+			name: "bad timezone",
+			body: `{"name": "a", "cron": "@daily", "job_type": "x", "payload_template": "1", "timezone": "Mars/Olympus"}`,
+			wantFields: map[string]string{
+				"timezone": `invalid timezone: "Mars/Olympus"`,
+			},
+		},
+		{
+			name: "template output is not JSON",
+			body: `{"name": "a", "cron": "@daily", "job_type": "x", "payload_template": "report {{.Schedule}}"}`,
+			wantFields: map[string]string{
+				"payload_template": "invalid payload template: output is not valid JSON",
+			},
+		},
+		{
+			name: "template output breaks the schema",
+			body: `{"name": "a", "cron": "@daily", "job_type": "report", "payload_template": "{}"}`,
+			wantFields: map[string]string{
+				"payload_template": "missing property 'at'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			NewWithRepository(repository.NewMemoryRepository(), &mockQueue{}, slog.Default()).
+				WithRegistry(registry).
+				RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/schedules", bytes.NewBufferString(tt.body))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var response struct {
+				Fields map[string]string `json:"fields"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantFields, response.Fields)
+		})
+	}
+}
+
+func TestSchedulerLeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemoryRepository()
+	router := gin.New()
+	NewWithRepository(repo, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/scheduler/leader", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, get().Code)
+
+	_, _, err := repo.AcquireLease("scheduler", "worker-a", time.Minute)
+	require.NoError(t, err)
+	w := get()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"leader":"worker-a"`)
+	assert.Contains(t, w.Body.String(), `"active":true`)
+
+	// Without the capability the endpoint isn't available
+	router = gin.New()
+	NewWithRepository(&mockRepository{}, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/scheduler/leader", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestWebhooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemoryRepository()
+	router := gin.New()
+	h := NewWithRepository(repo, &mockQueue{}, slog.Default())
+	h.RegisterRoutes(router)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without a secret to sign notifications with, callbacks are refused
+	w := do("POST", "/api/jobs", `{"type": "report", "data": {}, "callback_url": "https://example.com/hook"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "WEBHOOK_SECRET")
+	h.WithCallbacks()
+
+	w = do("POST", "/api/jobs", `{"type": "report", "data": {}, "callback_url": "ftp://example.com/hook"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"callbackurl"`)
+
+	w = do("POST", "/api/jobs", `{"type": "report", "data": {}, "callback_url": "https://example.com/hook"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var job models.Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "https://example.com/hook", job.CallbackURL)
+
+	// Cancelling finishes the job, which queues its notification
+	require.Equal(t, http.StatusOK, do("POST", "/api/jobs/"+job.ID.String()+"/cancel", "").Code)
+
+	var list struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+		Count      int                      `json:"count"`
+	}
+	w = do("GET", "/api/webhooks?job_id="+job.ID.String(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, 1, list.Count)
+	original := list.Deliveries[0]
+	assert.Equal(t, "job.cancelled", original.Event)
+	assert.Equal(t, models.WebhookStatusPending, original.Status)
+
+	w = do("POST", "/api/webhooks/"+original.ID.String()+"/replay", "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	var replay models.WebhookDelivery
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &replay))
+	assert.Equal(t, &original.ID, replay.ReplayOf)
+
+	w = do("GET", "/api/webhooks?status=pending", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, list.Count)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"unknown status", "GET", "/api/webhooks?status=lost", http.StatusBadRequest},
+		{"invalid job ID", "GET", "/api/webhooks?job_id=nope", http.StatusBadRequest},
+		{"limit too high", "GET", "/api/webhooks?limit=501", http.StatusBadRequest},
+		{"replay unknown", "POST", "/api/webhooks/" + uuid.NewString() + "/replay", http.StatusNotFound},
+		{"replay invalid ID", "POST", "/api/webhooks/nope/replay", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, do(tt.method, tt.path, "").Code)
+		})
+	}
+
+	// Without the capability the endpoints aren't available
+	router = gin.New()
+	NewWithRepository(&mockRepository{}, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+	assert.Equal(t, http.StatusNotImplemented, do("GET", "/api/webhooks", "").Code)
+}
+
+// readEvent reads the next Server-Sent Event from r, skipping comments
+func readEvent(t *testing.T, r *bufio.Reader) (string, models.JobEvent) {
+	t.Helper()
+
+	var name string
+	var event models.JobEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, event
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event))
+		}
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemoryRepository()
+	broker := events.NewBroker()
+	router := gin.New()
+	NewWithRepository(repo, &mockQueue{}, slog.Default()).WithEvents(broker).RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	open := func(path string) *http.Response {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	job := &models.Job{Type: "report"}
+	require.NoError(t, repo.CreateJob(job))
+
+	t.Run("job", func(t *testing.T) {
+		resp := open("/api/jobs/" + job.ID.String() + "/events")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		body := bufio.NewReader(resp.Body)
+
+		// The stream opens with the job's current status
+		name, event := readEvent(t, body)
+		assert.Equal(t, "status", name)
+		assert.Equal(t, job.ID, event.JobID)
+		assert.Equal(t, models.JobStatusPending, event.Status)
+
+		// Changes no newer than the job as read are skipped
+		broker.Publish(models.JobEvent{JobID: job.ID, Status: models.JobStatusPending, At: event.At})
+		broker.Publish(models.JobEvent{JobID: uuid.New(), Status: models.JobStatusProcessing, At: time.Now()})
+		broker.Publish(models.JobEvent{JobID: job.ID, Status: models.JobStatusProcessing,
+			PreviousStatus: models.JobStatusPending, At: event.At.Add(time.Second)})
+		broker.Publish(models.JobEvent{JobID: job.ID, Status: models.JobStatusCompleted,
+			PreviousStatus: models.JobStatusProcessing, At: event.At.Add(2 * time.Second)})
+
+		_, event = readEvent(t, body)
+		assert.Equal(t, models.JobStatusProcessing, event.Status)
+		assert.Equal(t, models.JobStatusPending, event.PreviousStatus)
+		_, event = readEvent(t, body)
+		assert.Equal(t, models.JobStatusCompleted, event.Status)
+
+		// and ends once the job finishes
+		_, err := body.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("finished job", func(t *testing.T) {
+		finished := &models.Job{Type: "report", Status: models.JobStatusFailed}
+		require.NoError(t, repo.CreateJob(finished))
+
+		body := bufio.NewReader(open("/api/jobs/" + finished.ID.String() + "/events").Body)
+		_, event := readEvent(t, body)
+		assert.Equal(t, models.JobStatusFailed, event.Status)
+		_, err := body.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("by type", func(t *testing.T) {
+		resp := open("/api/jobs/events?type=report,cleanup")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, 10*time.Millisecond)
+
+		other := uuid.New()
+		broker.Publish(models.JobEvent{JobID: uuid.New(), Type: "batch-import", Status: models.JobStatusPending})
+		broker.Publish(models.JobEvent{JobID: other, Type: "cleanup", Status: models.JobStatusPending})
+
+		body := bufio.NewReader(resp.Body)
+		_, event := readEvent(t, body)
+		assert.Equal(t, other, event.JobID)
+
+		// Closing the broker ends the stream, as on shutdown
+		broker.Close()
+		for {
+			if _, err := body.ReadString('\n'); err != nil {
+				assert.ErrorIs(t, err, io.EOF)
+				break
+			}
+		}
+	})
+
+	tests := []struct {
+		name       string
+		handler    *Handler
+		path       string
+		wantStatus int
+	}{
+		{"unknown job", NewWithRepository(repo, &mockQueue{}, slog.Default()).WithEvents(events.NewBroker()), "/api/jobs/" + uuid.NewString() + "/events", http.StatusNotFound},
+		{"invalid job ID", NewWithRepository(repo, &mockQueue{}, slog.Default()).WithEvents(events.NewBroker()), "/api/jobs/nope/events", http.StatusBadRequest},
+		{"not enabled", NewWithRepository(repo, &mockQueue{}, slog.Default()), "/api/jobs/events", http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			tt.handler.RegisterRoutes(router)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestWaitJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	repo := repository.NewMemoryRepository()
+	broker := events.NewBroker()
+	router := gin.New()
+	NewWithRepository(repo, &mockQueue{}, slog.Default()).WithEvents(broker).RegisterRoutes(router)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// doAsync makes the request once it is waiting on the broker
+	doAsync := func(method, path, body string) <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() { done <- do(method, path, body) }()
+		require.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, time.Millisecond)
+		return done
+	}
+	complete := func(job *models.Job) {
+		job.Status = models.JobStatusCompleted
+		require.NoError(t, repo.UpdateJob(job))
+		broker.Publish(models.NewJobEvent(job))
+	}
+	decode := func(w *httptest.ResponseRecorder) models.Job {
+		var job models.Job
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job
+	}
+
+	t.Run("finishes", func(t *testing.T) {
+		job := &models.Job{Type: "report"}
+		require.NoError(t, repo.CreateJob(job))
+
+		done := doAsync("GET", "/api/jobs/"+job.ID.String()+"/wait?timeout=5s", "")
+		// Changes short of finishing don't end the wait
+		broker.Publish(models.JobEvent{JobID: job.ID, Status: models.JobStatusProcessing})
+		complete(job)
+
+		w := <-done
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, models.JobStatusCompleted, decode(w).Status)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		job := &models.Job{Type: "report"}
+		require.NoError(t, repo.CreateJob(job))
+
+		w := do("GET", "/api/jobs/"+job.ID.String()+"/wait?timeout=20ms", "")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, models.JobStatusPending, decode(w).Status)
+	})
+
+	t.Run("already finished", func(t *testing.T) {
+		job := &models.Job{Type: "report", Status: models.JobStatusFailed}
+		require.NoError(t, repo.CreateJob(job))
+
+		w := do("GET", "/api/jobs/"+job.ID.String()+"/wait", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, models.JobStatusFailed, decode(w).Status)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		done := doAsync("POST", "/api/jobs?wait=true&timeout=5s", `{"type": "invoice", "data": {}}`)
+		page, err := repo.FindJobs(models.JobFilter{Types: []string{"invoice"}, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, page.Jobs, 1)
+		complete(&page.Jobs[0])
+
+		w := <-done
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, models.JobStatusCompleted, decode(w).Status)
+
+		w = do("POST", "/api/jobs?wait=true&timeout=20ms", `{"type": "report", "data": {}}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, models.JobStatusPending, decode(w).Status)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"unknown job", "GET", "/api/jobs/" + uuid.NewString() + "/wait", http.StatusNotFound},
+		{"invalid job ID", "GET", "/api/jobs/nope/wait", http.StatusBadRequest},
+		{"invalid timeout", "GET", "/api/jobs/" + uuid.NewString() + "/wait?timeout=soon", http.StatusBadRequest},
+		{"timeout too long", "GET", "/api/jobs/" + uuid.NewString() + "/wait?timeout=1m", http.StatusBadRequest},
+		{"invalid wait", "POST", "/api/jobs?wait=maybe", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, do(tt.method, tt.path, `{"type": "report", "data": {}}`).Code)
+		})
+	}
+
+	// Without events waiting isn't available, and no job is created
+	repo = repository.NewMemoryRepository()
+	router = gin.New()
+	NewWithRepository(repo, &mockQueue{}, slog.Default()).RegisterRoutes(router)
+	assert.Equal(t, http.StatusNotImplemented, do("POST", "/api/jobs?wait=true", `{"type": "report", "data": {}}`).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/api/jobs?wait=false", `{"type": "report", "data": {}}`).Code)
+	jobs, err := repo.ListJobs("", 10)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 1)
+}
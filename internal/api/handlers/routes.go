@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+)
+
+// RegisterRoutes mounts the health check, the Prometheus metrics and the
+// /api routes on router
+func (h *Handler) RegisterRoutes(router gin.IRouter) {
+	router.GET("/health", h.Health)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// API routes with /api prefix for ALB routing
+	api := router.Group("/api")
+	{
+		api.GET("/health", h.Health)
+		api.POST("/jobs", h.CreateJob)
+		api.GET("/jobs/events", h.StreamJobs)
+		api.GET("/jobs/:id", h.GetJob)
+		api.GET("/jobs/:id/events", h.StreamJob)
+		api.GET("/jobs/:id/wait", h.WaitJob)
+		api.POST("/jobs/:id/cancel", h.CancelJob)
+		api.POST("/jobs/:id/retry", h.RetryJob)
+		api.GET("/jobs", h.ListJobs)
+		api.GET("/dlq", h.ListDeadLetters)
+		api.POST("/dlq/redrive", h.RedriveDeadLetters)
+		api.GET("/schedules", h.ListSchedules)
+		api.POST("/schedules", h.CreateSchedule)
+		api.GET("/schedules/:id", h.GetSchedule)
+		api.PUT("/schedules/:id", h.UpdateSchedule)
+		api.DELETE("/schedules/:id", h.DeleteSchedule)
+		api.POST("/schedules/:id/pause", h.PauseSchedule)
+		api.POST("/schedules/:id/resume", h.ResumeSchedule)
+		api.GET("/schedules/:id/runs", h.ListScheduleRuns)
+		api.GET("/scheduler/leader", h.SchedulerLeader)
+		api.GET("/webhooks", h.ListWebhookDeliveries)
+		api.POST("/webhooks/:id/replay", h.ReplayWebhookDelivery)
+	}
+}
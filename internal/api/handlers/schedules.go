@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/api/middleware"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/scheduler"
+)
+
+const (
+	defaultScheduleRunLimit = 20
+	maxScheduleRunLimit     = 100
+)
+
+func (h *Handler) ListSchedules(c *gin.Context) {
+	if !h.schedulesAvailable(c) {
+		return
+	}
+
+	schedules, err := h.schedules.ListSchedules()
+	if err != nil {
+		h.logger.Error("failed to list schedules", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// CreateSchedule stores a new schedule. The worker's scheduler picks it up
+// on its next sync.
+func (h *Handler) CreateSchedule(c *gin.Context) {
+	if !h.schedulesAvailable(c) {
+		return
+	}
+
+	payload, ok := h.bindSchedule(c)
+	if !ok {
+		return
+	}
+
+	schedule := &models.Schedule{Enabled: true}
+	applySchedulePayload(schedule, payload)
+	schedule.SetDefaults()
+	if err := h.schedules.CreateSchedule(schedule); err != nil {
+		h.scheduleError(c, "failed to create schedule", "", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (h *Handler) GetSchedule(c *gin.Context) {
+	if !h.schedulesAvailable(c) {
+		return
+	}
+
+	id := c.Param("id")
+	schedule, err := h.schedules.GetSchedule(id)
+	if err != nil {
+		h.scheduleError(c, "failed to get schedule", id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateSchedule replaces a schedule. Leaving out enabled or the misfire
+// settings keeps them as they were.
+func (h *Handler) UpdateSchedule(c *gin.Context) {
+	if !h.schedulesAvailable(c) {
+		return
+	}
+
+	id := c.Param("id")
+	schedule, err := h.schedules.GetSchedule(id)
+	if err != nil {
+		h.scheduleError(c, "failed to get schedule", id, err)
+		return
+	}
+
+	payload, ok := h.bindSchedule(c)
+	if !ok {
+		return
+	}
+
+	applySchedulePayload(schedule, payload)
+	if err := h.schedules.UpdateSchedule(schedule); err != nil {
+		h.scheduleError(c, "failed to update schedule", id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *Handler) DeleteSchedule(c *gin.Context) {
+	if !h.schedulesAvailable(c) {
+		return
+	}
+
+	id := c.Param("id")
+	if err := h.schedules.DeleteSchedule(id); err != nil {
+		h.scheduleError(c, "failed to delete schedule", id, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListScheduleRuns returns a schedule's most recent runs, newest first
+func (h *Handler) ListScheduleRuns(c *gin.Context) {
+	if !h.schedulesAvailable(c) {
+		return
+	}
+
+	limit := defaultScheduleRunLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxScheduleRunLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxScheduleRunLimit)})
+			return
+		}
+		limit = n
+	}
+
+	id := c.Param("id")
+	if _, err := h.schedules.GetSchedule(id); err != nil {
+		h.scheduleError(c, "failed to get schedule", id, err)
+		return
+	}
+	runs, err := h.schedules.ListScheduleRuns(id, limit)
+	if err != nil {
+		h.scheduleError(c, "failed to list schedule runs", id, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs":  runs,
+		"count": len(runs),
+	})
+}
+
+func (h *Handler) PauseSchedule(c *gin.Context) {
+	h.setScheduleEnabled(c, false)
+}
+
+func (h *Handler) ResumeSchedule(c *gin.Context) {
+	h.setScheduleEnabled(c, true)
+}
+
+func (h *Handler) setScheduleEnabled(c *gin.Context, enabled bool) {
+	if !h.schedulesAvailable(c) {
+		return
+	}
+
+	id := c.Param("id")
+	schedule, err := h.schedules.GetSchedule(id)
+	if err != nil {
+		h.scheduleError(c, "failed to get schedule", id, err)
+		return
+	}
+
+	if schedule.Enabled != enabled {
+		schedule.Enabled = enabled
+		if err := h.schedules.UpdateSchedule(schedule); err != nil {
+			h.scheduleError(c, "failed to update schedule", id, err)
+			return
+		}
+		h.logger.Info("schedule toggled", "schedule", schedule.Name, "enabled", enabled)
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// bindSchedule reads and validates a schedule payload, responding 400 if it
+// is invalid
+func (h *Handler) bindSchedule(c *gin.Context) (models.SchedulePayload, bool) {
+	var payload models.SchedulePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		middleware.ValidationError(c, err)
+		return payload, false
+	}
+	if err := middleware.ValidateStruct(payload); err != nil {
+		middleware.ValidationError(c, err)
+		return payload, false
+	}
+	if payload.Timezone == "" {
+		payload.Timezone = "UTC"
+	}
+	if err := h.validateSchedule(payload); err != nil {
+		middleware.ValidationError(c, err)
+		return payload, false
+	}
+	return payload, true
+}
+
+// validateSchedule checks the cron expression and timezone parse, and that
+// the payload template renders data matching the job type's schema
+func (h *Handler) validateSchedule(payload models.SchedulePayload) error {
+	fields := middleware.FieldErrors{}
+	if _, err := scheduler.Parse(payload.Cron, payload.Timezone); err != nil {
+		if errors.Is(err, scheduler.ErrInvalidTimezone) {
+			fields["timezone"] = err.Error()
+		} else {
+			fields["cron"] = err.Error()
+		}
+	}
+
+	data, err := scheduler.RenderPayload(payload.PayloadTemplate, scheduler.TemplateData{
+		ScheduledAt: time.Now().UTC().Truncate(time.Second),
+		Schedule:    payload.Name,
+	})
+	if err != nil {
+		fields["payload_template"] = err.Error()
+	} else if err := h.registry.Validate(payload.JobType, data); err != nil {
+		var schemaErr *jobs.SchemaError
+		if !errors.As(err, &schemaErr) {
+			return err
+		}
+		for location, msg := range schemaErr.Fields {
+			fields["payload_template"+strings.TrimPrefix(location, "data")] = msg
+		}
+	}
+
+	if len(fields) > 0 {
+		return fields
+	}
+	return nil
+}
+
+// SchedulerLeader reports which worker instance holds the scheduler lease.
+// active is false once the lease has lapsed without another instance taking
+// it over yet.
+func (h *Handler) SchedulerLeader(c *gin.Context) {
+	if h.leases == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "leader election is not supported by this repository"})
+		return
+	}
+
+	lease, err := h.leases.GetLease(scheduler.LeaseName)
+	if err != nil {
+		if errors.Is(err, repository.ErrLeaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no scheduler leader elected"})
+			return
+		}
+		h.logger.Error("failed to get scheduler lease", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scheduler leader"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leader":      lease.Holder,
+		"acquired_at": lease.AcquiredAt,
+		"renewed_at":  lease.RenewedAt,
+		"expires_at":  lease.ExpiresAt,
+		"active":      lease.Active(time.Now()),
+	})
+}
+
+func applySchedulePayload(schedule *models.Schedule, payload models.SchedulePayload) {
+	schedule.Name = payload.Name
+	schedule.CronExpr = payload.Cron
+	schedule.JobType = payload.JobType
+	schedule.PayloadTemplate = payload.PayloadTemplate
+	schedule.Timezone = payload.Timezone
+	if payload.Enabled != nil {
+		schedule.Enabled = *payload.Enabled
+	}
+	if payload.MisfirePolicy != "" {
+		schedule.MisfirePolicy = payload.MisfirePolicy
+	}
+	if payload.CatchUpWindowSeconds != 0 {
+		schedule.CatchUpWindowSeconds = payload.CatchUpWindowSeconds
+	}
+	if payload.Priority != "" {
+		schedule.Priority = payload.Priority
+	}
+}
+
+func (h *Handler) schedulesAvailable(c *gin.Context) bool {
+	if h.schedules == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "schedules are not supported by this repository"})
+		return false
+	}
+	return true
+}
+
+func (h *Handler) scheduleError(c *gin.Context, msg, id string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule ID"})
+	case errors.Is(err, repository.ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+	case errors.Is(err, repository.ErrDuplicateScheduleName):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(msg, "error", err, "schedule_id", id)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+const (
+	defaultWaitTimeout = 30 * time.Second
+	// maxWaitTimeout keeps a silent response under the load balancer's 60s
+	// idle timeout
+	maxWaitTimeout = 50 * time.Second
+)
+
+// WaitJob responds once the job finishes, 200 with the job, or after the
+// timeout query parameter (30s by default) with the job as it then is and
+// 202. It waits on the job's status events rather than polling the database.
+func (h *Handler) WaitJob(c *gin.Context) {
+	if !h.eventsAvailable(c) {
+		return
+	}
+
+	timeout, err := parseWaitTimeout(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID"})
+		return
+	}
+
+	job, err := h.awaitJob(c, jobID, timeout)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		case c.Request.Context().Err() != nil:
+			// The client went away
+		default:
+			h.logger.Error("failed to wait for job", "error", err, "job_id", id)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job"})
+		}
+		return
+	}
+
+	if !job.IsFinished() {
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// awaitJob returns the job once it finishes, or as it is when timeout passes
+// or its events stop, e.g. for shutdown
+func (h *Handler) awaitJob(c *gin.Context, jobID uuid.UUID, timeout time.Duration) (*models.Job, error) {
+	ctx := c.Request.Context()
+	repo := h.repoFor(c)
+
+	// Subscribe before reading the job so no change falls in between
+	sub := h.broker.Subscribe(func(event models.JobEvent) bool {
+		return event.JobID == jobID && event.Final()
+	})
+	defer sub.Close()
+
+	job, err := repo.GetJob(jobID.String())
+	if err != nil || job.IsFinished() {
+		return job, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-sub.C:
+	case <-timer.C:
+	}
+	return repo.GetJob(jobID.String())
+}
+
+// parseWaitOptions reads the wait and timeout query parameters of a job
+// creation, which make it respond once the job finishes like WaitJob
+func parseWaitOptions(c *gin.Context) (bool, time.Duration, error) {
+	raw := c.Query("wait")
+	if raw == "" {
+		return false, 0, nil
+	}
+	wait, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, 0, errors.New("wait must be true or false")
+	}
+	if !wait {
+		return false, 0, nil
+	}
+	timeout, err := parseWaitTimeout(c)
+	return true, timeout, err
+}
+
+func parseWaitTimeout(c *gin.Context) (time.Duration, error) {
+	raw := c.Query("timeout")
+	if raw == "" {
+		return defaultWaitTimeout, nil
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil || timeout <= 0 || timeout > maxWaitTimeout {
+		return 0, fmt.Errorf("timeout must be a duration up to %s, such as 30s", maxWaitTimeout)
+	}
+	return timeout, nil
+}
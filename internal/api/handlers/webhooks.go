@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+const (
+	defaultWebhookLimit = 50
+	maxWebhookLimit     = 500
+)
+
+// ListWebhookDeliveries returns webhook deliveries, newest first, optionally
+// only those for one job (job_id) or in one status
+func (h *Handler) ListWebhookDeliveries(c *gin.Context) {
+	if !h.webhooksAvailable(c) {
+		return
+	}
+
+	filter := models.WebhookFilter{Limit: defaultWebhookLimit}
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxWebhookLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxWebhookLimit)})
+			return
+		}
+		filter.Limit = n
+	}
+	if raw := c.Query("job_id"); raw != "" {
+		jobID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID"})
+			return
+		}
+		filter.JobID = &jobID
+	}
+	switch status := models.WebhookStatus(c.Query("status")); status {
+	case "", models.WebhookStatusPending, models.WebhookStatusDelivered, models.WebhookStatusFailed:
+		filter.Status = status
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, delivered, failed"})
+		return
+	}
+
+	deliveries, err := h.webhooks.ListWebhookDeliveries(filter)
+	if err != nil {
+		h.logger.Error("failed to list webhook deliveries", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// ReplayWebhookDelivery sends a delivery's notification again as a new
+// delivery linked to it by replay_of, whatever became of the original
+func (h *Handler) ReplayWebhookDelivery(c *gin.Context) {
+	if !h.webhooksAvailable(c) {
+		return
+	}
+
+	id := c.Param("id")
+	replay, err := h.webhooks.ReplayWebhookDelivery(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidID):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook delivery ID"})
+		case errors.Is(err, repository.ErrWebhookNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook delivery not found"})
+		default:
+			h.logger.Error("failed to replay webhook delivery", "error", err, "delivery_id", id)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to replay webhook delivery"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, replay)
+}
+
+func (h *Handler) webhooksAvailable(c *gin.Context) bool {
+	if h.webhooks == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "webhooks are not supported by this repository"})
+		return false
+	}
+	return true
+}
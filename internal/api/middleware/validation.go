@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
 	
 	"github.com/gin-gonic/gin"
@@ -10,8 +13,37 @@ import (
 
 var validate = validator.New()
 
+// fieldErrors is implemented by errors that know which fields are invalid,
+// such as jobs.SchemaError
+type fieldErrors interface {
+	FieldErrors() map[string]string
+}
+
+// FieldErrors maps request fields to what is wrong with them, for checks
+// that validator tags can't express
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field, msg := range e {
+		fields = append(fields, field+": "+msg)
+	}
+	sort.Strings(fields)
+	return "validation failed: " + strings.Join(fields, "; ")
+}
+
+func (e FieldErrors) FieldErrors() map[string]string { return e }
+
 // ValidationError formats validation errors for API responses
 func ValidationError(c *gin.Context, err error) {
+	var fe fieldErrors
+	if errors.As(err, &fe) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "validation failed",
+			"fields": fe.FieldErrors(),
+		})
+		return
+	}
 	if ve, ok := err.(validator.ValidationErrors); ok {
 		errors := make(map[string]string)
 		for _, e := range ve {
@@ -20,9 +52,9 @@ func ValidationError(c *gin.Context, err error) {
 			case "required":
 				errors[field] = field + " is required"
 			case "min":
-				errors[field] = field + " must be at least " + e.Param() + " characters"
+				errors[field] = field + " must be at least " + e.Param() + unit(e)
 			case "max":
-				errors[field] = field + " must be at most " + e.Param() + " characters"
+				errors[field] = field + " must be at most " + e.Param() + unit(e)
 			default:
 				errors[field] = field + " is invalid"
 			}
@@ -36,6 +68,23 @@ func ValidationError(c *gin.Context, err error) {
 	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 }
 
+// unit describes what a min/max bound counts for the failing field
+func unit(e validator.FieldError) string {
+	switch e.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice:
+		if e.Type().Elem().Kind() == reflect.Uint8 {
+			return " bytes"
+		}
+		return " items"
+	case reflect.Map, reflect.Array:
+		return " items"
+	default:
+		return ""
+	}
+}
+
 // ValidateStruct validates any struct with validation tags
 func ValidateStruct(s interface{}) error {
 	return validate.Struct(s)
@@ -1,13 +1,15 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"time"
 
-	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
 )
 
 func Connect(databaseURL string) (*gorm.DB, error) {
@@ -17,6 +19,9 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to install query tracing: %w", err)
+	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -30,6 +35,30 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 	return db, nil
 }
 
+// Migrate applies pending migrations. It fails without changing anything
+// if the database was migrated past what this binary knows.
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&models.Job{})
-}
\ No newline at end of file
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(context.Background())
+	return err
+}
+
+// CheckSchema fails unless the database is at the latest known migration
+func CheckSchema(db *gorm.DB) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	return migrator.Check(context.Background())
+}
+
+func newMigrator(db *gorm.DB) (*Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	return NewMigrator(sqlDB)
+}
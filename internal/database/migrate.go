@@ -0,0 +1,337 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	ErrUnknownSchemaVersion = errors.New("database schema version is unknown to this binary")
+	ErrSchemaOutdated       = errors.New("database schema has pending migrations")
+	ErrUnknownMigration     = errors.New("unknown migration version")
+)
+
+// migrationLockID keys the Postgres advisory lock held while migrating, so
+// API pods starting together apply each migration once
+const migrationLockID = 4_713_925_001
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change with the SQL to undo it
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a known or applied migration and when it was applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the SQL migrations embedded in the binary. Each one runs
+// in its own transaction together with its schema_migrations row.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest returns the version the binary expects the schema to be at
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) ([]Migration, error) {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current == 0 {
+		return nil, nil
+	}
+	previous := 0
+	for _, migration := range m.migrations {
+		if migration.Version < current {
+			previous = migration.Version
+		}
+	}
+	return m.To(ctx, previous)
+}
+
+// To migrates up or down to target, where 0 reverts every migration. It
+// returns the migrations it ran, in the order it ran them, even on error.
+func (m *Migrator) To(ctx context.Context, target int) ([]Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Advisory locks belong to the session, so everything below runs on conn
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := currentVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	steps, up, err := m.plan(current, target)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range steps {
+		if err := apply(ctx, conn, migration, up); err != nil {
+			return steps[:i], err
+		}
+	}
+	return steps, nil
+}
+
+// Version returns the schema version recorded in the database, 0 if none
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	exists, err := migrationsTableExists(ctx, m.db)
+	if err != nil || !exists {
+		return 0, err
+	}
+	return currentVersion(ctx, m.db)
+}
+
+// Check fails unless the database is at exactly the latest known version
+func (m *Migrator) Check(ctx context.Context) error {
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case current == m.Latest():
+		return nil
+	case !m.known(current):
+		return fmt.Errorf("%w: at %d, latest known is %d", ErrUnknownSchemaVersion, current, m.Latest())
+	default:
+		return fmt.Errorf("%w: at %d, latest is %d", ErrSchemaOutdated, current, m.Latest())
+	}
+}
+
+// Status lists the known migrations, and any applied ones this binary
+// doesn't know, with when each was applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied := make(map[int]MigrationStatus)
+	exists, err := migrationsTableExists(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		rows, err := m.db.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var status MigrationStatus
+			var appliedAt time.Time
+			if err := rows.Scan(&status.Version, &status.Name, &appliedAt); err != nil {
+				return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+			}
+			status.AppliedAt = &appliedAt
+			applied[status.Version] = status
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = row.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		statuses = append(statuses, row)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+func (m *Migrator) known(version int) bool {
+	if version == 0 {
+		return true
+	}
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// plan returns the migrations taking the schema from current to target, and
+// whether they are applied up or reverted
+func (m *Migrator) plan(current, target int) ([]Migration, bool, error) {
+	if !m.known(current) {
+		return nil, false, fmt.Errorf("%w: at %d, latest known is %d", ErrUnknownSchemaVersion, current, m.Latest())
+	}
+	if !m.known(target) {
+		return nil, false, fmt.Errorf("%w: %d", ErrUnknownMigration, target)
+	}
+
+	var steps []Migration
+	if target >= current {
+		for _, migration := range m.migrations {
+			if migration.Version > current && migration.Version <= target {
+				steps = append(steps, migration)
+			}
+		}
+		return steps, true, nil
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if migration := m.migrations[i]; migration.Version <= current && migration.Version > target {
+			steps = append(steps, migration)
+		}
+	}
+	return steps, false, nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	script, record := migration.Down, "DELETE FROM schema_migrations WHERE version = $1"
+	args := []interface{}{migration.Version}
+	if up {
+		script, record = migration.Up, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+		args = append(args, migration.Name)
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("failed to run migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func currentVersion(ctx context.Context, q querier) (int, error) {
+	var version int
+	if err := q.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+func migrationsTableExists(ctx context.Context, q querier) (bool, error) {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	return exists, nil
+}
+
+// loadMigrations reads the NNNN_name.up.sql and NNNN_name.down.sql pairs in
+// dir, requiring both halves of every version
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		if version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, migration.Name, match[2])
+		}
+
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
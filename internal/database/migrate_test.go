@@ -0,0 +1,109 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "versions are sequential")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{
+			name: "missing down",
+			files: fstest.MapFS{
+				"m/0001_create.up.sql": {Data: []byte("CREATE TABLE t ()")},
+			},
+		},
+		{
+			name: "mismatched names",
+			files: fstest.MapFS{
+				"m/0001_create.up.sql":  {Data: []byte("CREATE TABLE t ()")},
+				"m/0001_other.down.sql": {Data: []byte("DROP TABLE t")},
+			},
+		},
+		{
+			name: "bad file name",
+			files: fstest.MapFS{
+				"m/create.sql": {Data: []byte("CREATE TABLE t ()")},
+			},
+		},
+		{
+			name: "version zero",
+			files: fstest.MapFS{
+				"m/0000_create.up.sql":   {Data: []byte("CREATE TABLE t ()")},
+				"m/0000_create.down.sql": {Data: []byte("DROP TABLE t")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files, "m")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMigrator_Plan(t *testing.T) {
+	files := fstest.MapFS{}
+	for _, name := range []string{"0001_a", "0002_b", "0005_c"} {
+		files["m/"+name+".up.sql"] = &fstest.MapFile{Data: []byte("up " + name)}
+		files["m/"+name+".down.sql"] = &fstest.MapFile{Data: []byte("down " + name)}
+	}
+	migrations, err := loadMigrations(files, "m")
+	require.NoError(t, err)
+	m := &Migrator{migrations: migrations}
+	assert.Equal(t, 5, m.Latest())
+
+	tests := []struct {
+		name    string
+		current int
+		target  int
+		wantUp  bool
+		wantRun []int
+		wantErr error
+	}{
+		{"fresh database to latest", 0, 5, true, []int{1, 2, 5}, nil},
+		{"partially migrated", 2, 5, true, []int{5}, nil},
+		{"up to date", 5, 5, true, nil, nil},
+		{"down one", 5, 2, false, []int{5}, nil},
+		{"down to nothing", 5, 0, false, []int{5, 2, 1}, nil},
+		{"unknown target", 0, 3, false, nil, ErrUnknownMigration},
+		{"database ahead of binary", 6, 5, false, nil, ErrUnknownSchemaVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, up, err := m.plan(tt.current, tt.target)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantUp, up)
+
+			var versions []int
+			for _, step := range steps {
+				versions = append(versions, step.Version)
+			}
+			assert.Equal(t, tt.wantRun, versions)
+		})
+	}
+}
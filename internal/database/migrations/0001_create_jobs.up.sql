@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    type VARCHAR(100) NOT NULL,
+    data JSONB NOT NULL,
+    result TEXT,
+    error TEXT,
+    attempts BIGINT NOT NULL DEFAULT 0,
+    max_attempts BIGINT NOT NULL DEFAULT 3,
+    attempt_history TEXT,
+    worker_id VARCHAR(255),
+    lease_expires_at TIMESTAMPTZ,
+    cancel_requested_at TIMESTAMPTZ,
+    parent_id UUID,
+    retried_by VARCHAR(255),
+    retried_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+-- Databases created by GORM AutoMigrate before versioned migrations may
+-- predate some of the columns above, or still store data as text
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS attempts BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS max_attempts BIGINT NOT NULL DEFAULT 3;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS attempt_history TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS worker_id VARCHAR(255);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS cancel_requested_at TIMESTAMPTZ;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS parent_id UUID;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS retried_by VARCHAR(255);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS retried_at TIMESTAMPTZ;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'jobs' AND column_name = 'data' AND data_type = 'text') THEN
+        ALTER TABLE jobs ALTER COLUMN data TYPE JSONB USING to_jsonb(data);
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_created_at_id ON jobs (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_jobs_parent_id ON jobs (parent_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_data ON jobs USING GIN (data);
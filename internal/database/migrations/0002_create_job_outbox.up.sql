@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS job_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL,
+    attempts BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_outbox_job_id ON job_outbox (job_id);
+CREATE INDEX IF NOT EXISTS idx_job_outbox_next_attempt_at ON job_outbox (next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_job_outbox_delivered_at ON job_outbox (delivered_at);
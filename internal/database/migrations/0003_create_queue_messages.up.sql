@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS queue_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    queue VARCHAR(100) NOT NULL,
+    body TEXT NOT NULL,
+    receipt_handle UUID,
+    receive_count BIGINT NOT NULL DEFAULT 0,
+    visible_at TIMESTAMPTZ NOT NULL,
+    dead_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_messages_visible ON queue_messages (queue, visible_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_queue_messages_receipt_handle ON queue_messages (receipt_handle);
+CREATE INDEX IF NOT EXISTS idx_queue_messages_dead_at ON queue_messages (dead_at);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_jobs_updated_at ON jobs;
+DROP FUNCTION IF EXISTS update_updated_at_column();
+DROP INDEX IF EXISTS idx_jobs_status;
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_status_check;
@@ -0,0 +1,24 @@
+CREATE TABLE schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    cron_expr VARCHAR(100) NOT NULL,
+    job_type VARCHAR(100) NOT NULL,
+    payload_template TEXT NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_schedules_name ON schedules (name);
+
+-- The scheduler reloads a schedule when its updated_at changes, including
+-- after edits made directly in SQL
+CREATE TRIGGER update_schedules_updated_at BEFORE UPDATE ON schedules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- The schedules the scheduler used to hard-code
+INSERT INTO schedules (name, cron_expr, job_type, payload_template) VALUES
+    ('cleanup', '0 */5 * * * *', 'cleanup', '"Remove completed jobs older than 7 days"'),
+    ('health-report', '0 0 * * * *', 'health-report', '"Generate system health report at {{.ScheduledAt.Format "2006-01-02T15:04:05Z07:00"}}"'),
+    ('data-aggregation', '0 0 2 * * *', 'data-aggregation', '"Aggregate daily metrics and statistics"');
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS schedule_runs;
+
+ALTER TABLE schedules
+    DROP COLUMN IF EXISTS catch_up_window_seconds,
+    DROP COLUMN IF EXISTS misfire_policy;
@@ -0,0 +1,23 @@
+ALTER TABLE schedules
+    ADD COLUMN misfire_policy VARCHAR(16) NOT NULL DEFAULT 'skip'
+        CONSTRAINT schedules_misfire_policy_check CHECK (misfire_policy IN ('skip', 'run_once', 'run_all')),
+    ADD COLUMN catch_up_window_seconds INTEGER NOT NULL DEFAULT 86400
+        CONSTRAINT schedules_catch_up_window_check CHECK (catch_up_window_seconds > 0);
+
+-- A daily aggregation missed while the worker was down still needs running
+UPDATE schedules SET misfire_policy = 'run_once' WHERE name = 'data-aggregation';
+
+CREATE TABLE schedule_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    schedule_id UUID NOT NULL REFERENCES schedules (id) ON DELETE CASCADE,
+    planned_at TIMESTAMPTZ NOT NULL,
+    fired_at TIMESTAMPTZ NOT NULL,
+    job_id UUID,
+    error TEXT,
+    catch_up BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One run per planned time, so a firing is never repeated, even across a
+-- change of scheduler leader. Also serves the latest-run lookup.
+CREATE UNIQUE INDEX idx_schedule_runs_planned ON schedule_runs (schedule_id, planned_at);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_jobs_scheduled_run_at;
+
+-- Held jobs can't be represented without run_at; queue them now instead
+INSERT INTO job_outbox (job_id, next_attempt_at, created_at, updated_at)
+    SELECT id, now(), now(), now() FROM jobs WHERE status = 'scheduled';
+UPDATE jobs SET status = 'pending' WHERE status = 'scheduled';
+
+ALTER TABLE jobs DROP CONSTRAINT jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'retrying', 'cancelled'));
+
+ALTER TABLE job_outbox DROP COLUMN IF EXISTS run_at;
+ALTER TABLE jobs DROP COLUMN IF EXISTS run_at;
@@ -0,0 +1,9 @@
+ALTER TABLE jobs ADD COLUMN run_at TIMESTAMPTZ;
+ALTER TABLE job_outbox ADD COLUMN run_at TIMESTAMPTZ;
+
+ALTER TABLE jobs DROP CONSTRAINT jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check
+    CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'retrying', 'cancelled', 'scheduled'));
+
+-- Promotion looks for held jobs coming due
+CREATE INDEX idx_jobs_scheduled_run_at ON jobs (run_at) WHERE status = 'scheduled';
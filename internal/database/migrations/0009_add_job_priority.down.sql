@@ -0,0 +1,3 @@
+ALTER TABLE schedules DROP COLUMN IF EXISTS priority;
+ALTER TABLE job_outbox DROP COLUMN IF EXISTS priority;
+ALTER TABLE jobs DROP COLUMN IF EXISTS priority;
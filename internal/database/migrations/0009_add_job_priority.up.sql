@@ -0,0 +1,13 @@
+ALTER TABLE jobs
+    ADD COLUMN priority VARCHAR(16) NOT NULL DEFAULT 'normal'
+        CONSTRAINT jobs_priority_check CHECK (priority IN ('high', 'normal', 'low'));
+
+-- The relay publishes each message to the queue for its job's priority
+ALTER TABLE job_outbox ADD COLUMN priority VARCHAR(16) NOT NULL DEFAULT 'normal';
+
+ALTER TABLE schedules
+    ADD COLUMN priority VARCHAR(16) NOT NULL DEFAULT 'normal'
+        CONSTRAINT schedules_priority_check CHECK (priority IN ('high', 'normal', 'low'));
+
+-- Housekeeping shouldn't hold up the jobs users submit
+UPDATE schedules SET priority = 'low' WHERE name IN ('cleanup', 'health-report');
@@ -0,0 +1,3 @@
+ALTER TABLE queue_messages DROP COLUMN IF EXISTS attributes;
+ALTER TABLE job_outbox DROP COLUMN IF EXISTS trace_context;
+ALTER TABLE jobs DROP COLUMN IF EXISTS trace_context;
@@ -0,0 +1,7 @@
+-- W3C trace context of the request that created the job, carried through
+-- the outbox and the queue so the worker continues the same trace
+ALTER TABLE jobs ADD COLUMN trace_context JSONB;
+ALTER TABLE job_outbox ADD COLUMN trace_context JSONB;
+
+-- Message attributes for the Postgres queue, as SQS has
+ALTER TABLE queue_messages ADD COLUMN attributes JSONB;
@@ -0,0 +1,26 @@
+ALTER TABLE jobs ADD COLUMN callback_url TEXT;
+
+-- One row per notification of a finished job, written in the same
+-- transaction as the job's final status and sent by the worker's webhook
+-- dispatcher. Replays add a row linked by replay_of.
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    job_id UUID NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    response_status INTEGER,
+    delivered_at TIMESTAMPTZ,
+    replay_of UUID REFERENCES webhook_deliveries (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_deliveries_job_id ON webhook_deliveries (job_id);
+-- The dispatcher's due-delivery scan
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS notify_jobs_status ON jobs;
+DROP FUNCTION IF EXISTS notify_job_status_change();
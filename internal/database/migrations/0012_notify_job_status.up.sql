@@ -0,0 +1,23 @@
+-- Announces every job status change on the job_events channel, so API
+-- instances can stream them to clients whichever process saved the job.
+-- NOTIFY is transactional: listeners only hear of committed changes.
+CREATE OR REPLACE FUNCTION notify_job_status_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.status IS NOT DISTINCT FROM NEW.status THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('job_events', json_build_object(
+        'job_id', NEW.id,
+        'type', NEW.type,
+        'status', NEW.status,
+        'previous_status', CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END,
+        'attempts', NEW.attempts,
+        'at', NEW.updated_at
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_jobs_status AFTER INSERT OR UPDATE OF status ON jobs
+    FOR EACH ROW EXECUTE FUNCTION notify_job_status_change();
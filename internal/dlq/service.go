@@ -0,0 +1,115 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+// Entry is a dead-lettered message joined with its job row. Job is nil when
+// the message doesn't reference a job that still exists.
+type Entry struct {
+	Message queue.DeadLetter `json:"message"`
+	Job     *models.Job      `json:"job,omitempty"`
+}
+
+// Service backs the DLQ API endpoints and the jobctl dlq subcommand
+type Service struct {
+	queue  interfaces.DeadLetterQueue
+	repo   interfaces.Repository
+	logger *slog.Logger
+}
+
+func NewService(queue interfaces.DeadLetterQueue, repo interfaces.Repository, logger *slog.Logger) *Service {
+	return &Service{
+		queue:  queue,
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// List returns up to limit dead letters with their jobs
+func (s *Service) List(ctx context.Context, limit int) ([]Entry, error) {
+	letters, err := s.queue.PeekDeadLetters(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(letters))
+	for _, letter := range letters {
+		entry := Entry{Message: letter}
+		if letter.JobID != "" {
+			job, err := s.repo.GetJob(letter.JobID)
+			switch {
+			case err == nil:
+				entry.Job = job
+			case errors.Is(err, repository.ErrJobNotFound), errors.Is(err, repository.ErrInvalidID):
+			default:
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// maxRedriveScan bounds how many dead letters a redrive looks through for
+// the selected messages' jobs
+const maxRedriveScan = 1000
+
+// Redrive moves the selected messages back to the queue they came from.
+// Each one's job is reset to pending first, by a conditional update that
+// leaves jobs alone that completed, were cancelled or are held by a live
+// worker; messages of those jobs stay dead-lettered. Messages without a job
+// are moved as they are, and those the scan doesn't find are skipped.
+func (s *Service) Redrive(ctx context.Context, messageIDs []string) ([]queue.DeadLetter, error) {
+	letters, err := s.queue.PeekDeadLetters(ctx, maxRedriveScan)
+	if err != nil {
+		return nil, err
+	}
+	jobIDs := make(map[string]string, len(letters))
+	for _, letter := range letters {
+		jobIDs[letter.MessageID] = letter.JobID
+	}
+
+	redrive := make([]string, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		jobID, found := jobIDs[id]
+		if !found {
+			s.logger.Warn("dead letter not found", "message_id", id)
+			continue
+		}
+		if jobID == "" {
+			redrive = append(redrive, id)
+			continue
+		}
+
+		err := s.repo.ResetJobForRedrive(jobID)
+		switch {
+		case err == nil:
+			redrive = append(redrive, id)
+		case errors.Is(err, repository.ErrJobNotRedrivable):
+			s.logger.Info("Not redriving dead letter, its job is finished or running", "message_id", id, "job_id", jobID)
+		case errors.Is(err, repository.ErrJobNotFound), errors.Is(err, repository.ErrInvalidID):
+			s.logger.Warn("dead letter has no job", "message_id", id, "job_id", jobID, "error", err)
+			redrive = append(redrive, id)
+		default:
+			return nil, err
+		}
+	}
+	if len(redrive) == 0 {
+		return nil, nil
+	}
+
+	moved, err := s.queue.RedriveDeadLetters(ctx, redrive)
+	for _, letter := range moved {
+		s.logger.Info("Redrove dead letter", "message_id", letter.MessageID, "job_id", letter.JobID)
+	}
+	return moved, err
+}
@@ -0,0 +1,156 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+type fakeDLQ struct {
+	letters []queue.DeadLetter
+	err     error
+}
+
+func (f *fakeDLQ) PeekDeadLetters(ctx context.Context, maxMessages int) ([]queue.DeadLetter, error) {
+	if len(f.letters) > maxMessages {
+		return f.letters[:maxMessages], nil
+	}
+	return f.letters, nil
+}
+
+func (f *fakeDLQ) RedriveDeadLetters(ctx context.Context, messageIDs []string) ([]queue.DeadLetter, error) {
+	var moved []queue.DeadLetter
+	for _, letter := range f.letters {
+		for _, id := range messageIDs {
+			if letter.MessageID == id {
+				moved = append(moved, letter)
+			}
+		}
+	}
+	return moved, f.err
+}
+
+type fakeRepo struct {
+	jobs  map[string]*models.Job
+	reset []string
+}
+
+func (r *fakeRepo) CreateJob(job *models.Job) error { return nil }
+
+func (r *fakeRepo) GetJob(id string) (*models.Job, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, repository.ErrInvalidID
+	}
+	if job, ok := r.jobs[id]; ok {
+		copied := *job
+		return &copied, nil
+	}
+	return nil, repository.ErrJobNotFound
+}
+
+func (r *fakeRepo) UpdateJob(job *models.Job) error { return nil }
+
+func (r *fakeRepo) ResetJobForRedrive(id string) error {
+	job, ok := r.jobs[id]
+	if !ok {
+		return repository.ErrJobNotFound
+	}
+	if !job.Redrivable(time.Now()) {
+		return repository.ErrJobNotRedrivable
+	}
+	job.Status = models.JobStatusPending
+	job.Error = ""
+	job.WorkerID = ""
+	job.LeaseExpiresAt = nil
+	r.reset = append(r.reset, id)
+	return nil
+}
+
+func (r *fakeRepo) ListJobs(status string, limit int) ([]models.Job, error) { return nil, nil }
+func (r *fakeRepo) FindJobs(filter models.JobFilter) (*models.JobPage, error) {
+	return &models.JobPage{}, nil
+}
+
+func (r *fakeRepo) GetPendingJobs(limit int) ([]models.Job, error) { return nil, nil }
+
+func (r *fakeRepo) ClaimJob(id string, workerID string, lease time.Duration) (*models.Job, error) {
+	return nil, repository.ErrJobNotClaimable
+}
+
+func (r *fakeRepo) RenewJobLease(id string, workerID string, lease time.Duration) error { return nil }
+
+func (r *fakeRepo) FinishJob(job *models.Job) error { return repository.ErrLeaseLost }
+
+func (r *fakeRepo) CancelJob(id string) (*models.Job, error) { return nil, repository.ErrJobFinished }
+func (r *fakeRepo) RetryJob(id string, retriedBy string) (*models.Job, error) {
+	return nil, repository.ErrJobNotRerunnable
+}
+
+func TestService_List(t *testing.T) {
+	failed := &models.Job{ID: uuid.New(), Type: "batch-import", Status: models.JobStatusProcessing}
+	repo := &fakeRepo{jobs: map[string]*models.Job{failed.ID.String(): failed}}
+	q := &fakeDLQ{letters: []queue.DeadLetter{
+		{MessageID: "m1", JobID: failed.ID.String()},
+		{MessageID: "m2", JobID: uuid.New().String()},
+		{MessageID: "m3", Body: "not json"},
+	}}
+
+	entries, err := NewService(q, repo, slog.Default()).List(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, failed.ID, entries[0].Job.ID)
+	assert.Nil(t, entries[1].Job, "deleted job")
+	assert.Nil(t, entries[2].Job, "unparseable message")
+}
+
+func TestService_Redrive(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	live := time.Now().Add(time.Minute)
+	stuck := &models.Job{ID: uuid.New(), Status: models.JobStatusFailed, Error: "boom"}
+	abandoned := &models.Job{ID: uuid.New(), Status: models.JobStatusProcessing, WorkerID: "dead", LeaseExpiresAt: &expired}
+	running := &models.Job{ID: uuid.New(), Status: models.JobStatusProcessing, WorkerID: "alive", LeaseExpiresAt: &live}
+	done := &models.Job{ID: uuid.New(), Status: models.JobStatusCompleted}
+	repo := &fakeRepo{jobs: map[string]*models.Job{
+		stuck.ID.String():     stuck,
+		abandoned.ID.String(): abandoned,
+		running.ID.String():   running,
+		done.ID.String():      done,
+	}}
+	q := &fakeDLQ{
+		letters: []queue.DeadLetter{
+			{MessageID: "m1", JobID: stuck.ID.String()},
+			{MessageID: "m2", JobID: abandoned.ID.String()},
+			{MessageID: "m3", JobID: running.ID.String()},
+			{MessageID: "m4", JobID: done.ID.String()},
+			{MessageID: "m5", Body: "not json"},
+		},
+		err: errors.New("partial"),
+	}
+
+	moved, err := NewService(q, repo, slog.Default()).Redrive(context.Background(), []string{"m1", "m2", "m3", "m4", "m5", "missing"})
+	assert.EqualError(t, err, "partial")
+
+	// Jobs that finished or that a live worker holds keep their messages
+	// dead-lettered and aren't touched
+	var ids []string
+	for _, letter := range moved {
+		ids = append(ids, letter.MessageID)
+	}
+	assert.Equal(t, []string{"m1", "m2", "m5"}, ids)
+	assert.Equal(t, []string{stuck.ID.String(), abandoned.ID.String()}, repo.reset)
+	assert.Equal(t, models.JobStatusPending, stuck.Status)
+	assert.Empty(t, stuck.Error)
+	assert.Empty(t, abandoned.WorkerID)
+	assert.Nil(t, abandoned.LeaseExpiresAt)
+	assert.Equal(t, "alive", running.WorkerID)
+	assert.Equal(t, models.JobStatusCompleted, done.Status)
+}
@@ -0,0 +1,112 @@
+// Package events streams job status changes to the API's clients. The
+// database announces each change, a Listener hears it and a Broker passes it
+// to the subscribed streams.
+package events
+
+import (
+	"sync"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by
+// before it is dropped
+const subscriberBuffer = 64
+
+// Broker fans job events out to subscribers
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events matching its filter on C until it is
+// closed, which closes C
+type Subscription struct {
+	C <-chan models.JobEvent
+
+	ch     chan models.JobEvent
+	match  func(models.JobEvent) bool
+	broker *Broker
+}
+
+// Subscribe starts receiving the events for which match returns true, or all
+// of them if match is nil. After Close the subscription is already closed.
+func (b *Broker) Subscribe(match func(models.JobEvent) bool) *Subscription {
+	ch := make(chan models.JobEvent, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, match: match, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish passes event to the matching subscribers. One that has fallen too
+// far behind is closed instead, so its client reconnects and catches up
+// rather than silently missing changes.
+func (b *Broker) Publish(event models.JobEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if sub.match != nil && !sub.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Disconnect closes every current subscription, for when events may have
+// been missed, but still accepts new ones
+func (b *Broker) Disconnect() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// Close closes every subscription and any made later, e.g. so open streams
+// don't hold up the server's shutdown
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// Subscribers returns the number of open subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// remove closes sub unless it was already; b.mu must be held
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+func TestBroker_Publish(t *testing.T) {
+	broker := NewBroker()
+	all := broker.Subscribe(nil)
+	reports := broker.Subscribe(func(event models.JobEvent) bool { return event.Type == "report" })
+	defer all.Close()
+	defer reports.Close()
+
+	broker.Publish(models.JobEvent{JobID: uuid.New(), Type: "cleanup"})
+	broker.Publish(models.JobEvent{JobID: uuid.New(), Type: "report"})
+
+	assert.Len(t, all.C, 2)
+	require.Len(t, reports.C, 1)
+	assert.Equal(t, "report", (<-reports.C).Type)
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker()
+	slow := broker.Subscribe(nil)
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		broker.Publish(models.JobEvent{JobID: uuid.New()})
+	}
+
+	// The buffered events can still be read, then the channel is closed
+	received := 0
+	for range slow.C {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+	assert.Zero(t, broker.Subscribers())
+	slow.Close()
+}
+
+func TestBroker_Close(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(nil)
+
+	broker.Disconnect()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	// Disconnecting still takes new subscribers, closing doesn't
+	sub = broker.Subscribe(nil)
+	assert.Equal(t, 1, broker.Subscribers())
+	broker.Close()
+	_, ok = <-sub.C
+	assert.False(t, ok)
+
+	sub = broker.Subscribe(nil)
+	_, ok = <-sub.C
+	assert.False(t, ok)
+	assert.Zero(t, broker.Subscribers())
+}
+
+func TestDecodeEvent(t *testing.T) {
+	// As built by the notify_job_status_change trigger
+	event, err := decodeEvent(`{"job_id" : "6f1c4bd3-0a55-4d0e-9a38-7a4b1e8d5c21", "type" : "report", "status" : "processing", "previous_status" : "pending", "attempts" : 1, "at" : "2026-10-16T09:30:00.123456+00:00"}`)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.MustParse("6f1c4bd3-0a55-4d0e-9a38-7a4b1e8d5c21"), event.JobID)
+	assert.Equal(t, models.JobStatusProcessing, event.Status)
+	assert.Equal(t, models.JobStatusPending, event.PreviousStatus)
+	assert.Equal(t, 1, event.Attempts)
+	assert.Equal(t, 123456000, event.At.Nanosecond())
+
+	// A new job has no previous status
+	event, err = decodeEvent(`{"job_id" : "6f1c4bd3-0a55-4d0e-9a38-7a4b1e8d5c21", "type" : "report", "status" : "pending", "previous_status" : null, "attempts" : 0, "at" : "2026-10-16T09:30:00+00:00"}`)
+	require.NoError(t, err)
+	assert.Empty(t, event.PreviousStatus)
+
+	_, err = decodeEvent("not json")
+	assert.Error(t, err)
+}
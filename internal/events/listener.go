@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+// Channel is the Postgres notification channel the jobs table's trigger
+// announces status changes on
+const Channel = "job_events"
+
+const (
+	baseBackoff = time.Second
+	maxBackoff  = 30 * time.Second
+)
+
+// Listener passes the job status changes announced by Postgres to a Broker.
+// It holds its own connection, outside the pool, for LISTEN.
+type Listener struct {
+	databaseURL string
+	broker      *Broker
+	logger      *slog.Logger
+}
+
+func NewListener(databaseURL string, broker *Broker, logger *slog.Logger) *Listener {
+	return &Listener{
+		databaseURL: databaseURL,
+		broker:      broker,
+		logger:      logger,
+	}
+}
+
+// Start listens until ctx is done, reconnecting with backoff whenever the
+// connection is lost. Changes made while disconnected are never announced,
+// so open streams are then closed for their clients to reconnect and catch up.
+func (l *Listener) Start(ctx context.Context) error {
+	l.logger.Info("Job event listener started")
+
+	delay := baseBackoff
+	for {
+		connected, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			l.logger.Info("Job event listener shutting down")
+			return nil
+		}
+		if connected {
+			l.broker.Disconnect()
+			delay = baseBackoff
+		}
+		l.logger.Error("job event listener disconnected, reconnecting", "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			l.logger.Info("Job event listener shutting down")
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+// listen relays notifications until the connection fails, reporting whether
+// it was established
+func (l *Listener) listen(ctx context.Context) (bool, error) {
+	conn, err := pgx.Connect(ctx, l.databaseURL)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{Channel}.Sanitize()); err != nil {
+		return false, fmt.Errorf("failed to listen on %s: %w", Channel, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		event, err := decodeEvent(notification.Payload)
+		if err != nil {
+			l.logger.Warn("ignoring malformed job event", "error", err, "payload", notification.Payload)
+			continue
+		}
+		l.broker.Publish(event)
+	}
+}
+
+func decodeEvent(payload string) (models.JobEvent, error) {
+	var event models.JobEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return event, fmt.Errorf("failed to decode job event: %w", err)
+	}
+	return event, nil
+}
@@ -2,9 +2,11 @@ package interfaces
 
 import (
 	"context"
+	"time"
 	
-	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/google/uuid"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
 )
 
 // Repository defines database operations
@@ -13,17 +15,109 @@ type Repository interface {
 	GetJob(id string) (*models.Job, error)
 	UpdateJob(job *models.Job) error
 	ListJobs(status string, limit int) ([]models.Job, error)
+	// FindJobs returns a page of jobs matching filter
+	FindJobs(filter models.JobFilter) (*models.JobPage, error)
 	GetPendingJobs(limit int) ([]models.Job, error)
+	// ClaimJob atomically marks a claimable job as processing by workerID
+	// for lease and counts the attempt
+	ClaimJob(id string, workerID string, lease time.Duration) (*models.Job, error)
+	// RenewJobLease extends workerID's lease on a job it is processing
+	RenewJobLease(id string, workerID string, lease time.Duration) error
+	// ResetJobForRedrive makes a dead-lettered job pending again, unless it
+	// finished or a live worker holds it
+	ResetJobForRedrive(id string) error
+	// FinishJob saves the outcome of an attempt if job.WorkerID still holds
+	// the job, and fails with a lost lease error otherwise
+	FinishJob(job *models.Job) error
+	// CancelJob cancels a job no worker is running, or asks the worker
+	// running it to stop
+	CancelJob(id string) (*models.Job, error)
+	// RetryJob re-runs a failed or cancelled job as a new job linked to it
+	RetryJob(id string, retriedBy string) (*models.Job, error)
+}
+
+// ContextRepository is a Repository that can run its queries with a
+// caller's context, so they are traced as part of the caller's span
+type ContextRepository interface {
+	WithContext(ctx context.Context) Repository
+}
+
+// Outbox defines transactional outbox operations used by the relay
+type Outbox interface {
+	ClaimOutboxMessages(limit int, lease time.Duration) ([]models.OutboxMessage, error)
+	MarkOutboxDelivered(id uuid.UUID) error
+	MarkOutboxFailed(id uuid.UUID, reason string, nextAttemptAt time.Time) error
+	CountUndeliveredOutbox() (int64, error)
+}
+
+// OutboxPurger removes outbox messages delivered before a cutoff
+type OutboxPurger interface {
+	PurgeDeliveredOutbox(before time.Time) (int64, error)
+}
+
+// ScheduledJobs queues jobs held in the database as they come due
+type ScheduledJobs interface {
+	PromoteScheduledJobs(dueBy time.Time, limit int) (int, error)
+}
+
+// ScheduleRepository stores the cron schedules the scheduler runs
+type ScheduleRepository interface {
+	CreateSchedule(schedule *models.Schedule) error
+	GetSchedule(id string) (*models.Schedule, error)
+	ListSchedules() ([]models.Schedule, error)
+	UpdateSchedule(schedule *models.Schedule) error
+	DeleteSchedule(id string) error
+	RecordScheduleRun(run *models.ScheduleRun, job *models.Job) error
+	ListScheduleRuns(scheduleID string, limit int) ([]models.ScheduleRun, error)
+}
+
+// LeaseRepository stores the time-limited leases used to elect a single
+// instance for a role, such as running the scheduler
+type LeaseRepository interface {
+	AcquireLease(name, holder string, ttl time.Duration) (*models.Lease, bool, error)
+	ReleaseLease(name, holder string) error
+	GetLease(name string) (*models.Lease, error)
+}
+
+// WebhookDeliveries are the notifications of finished jobs sent by the
+// webhook dispatcher
+type WebhookDeliveries interface {
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	UpdateWebhookDelivery(delivery *models.WebhookDelivery) error
+}
+
+// WebhookRepository lists and replays webhook deliveries
+type WebhookRepository interface {
+	ListWebhookDeliveries(filter models.WebhookFilter) ([]models.WebhookDelivery, error)
+	ReplayWebhookDelivery(id string) (*models.WebhookDelivery, error)
 }
 
 // Queue defines message queue operations
 type Queue interface {
-	SendMessage(ctx context.Context, jobID string) error
-	ReceiveMessages(ctx context.Context) ([]types.Message, error)
-	DeleteMessage(ctx context.Context, receiptHandle string) error
+	SendMessage(ctx context.Context, jobID string, opts ...queue.SendOption) error
+	ReceiveMessages(ctx context.Context, maxMessages int) ([]queue.Message, error)
+	// Ack removes a handled message from the queue
+	Ack(ctx context.Context, msg queue.Message) error
+	// Nack gives a message back to the queue, visible again after delay
+	Nack(ctx context.Context, msg queue.Message, delay time.Duration) error
+	// Extend keeps a received message hidden for timeout from now
+	Extend(ctx context.Context, msg queue.Message, timeout time.Duration) error
+}
+
+// PollingQueue is a queue whose receives can wait less than its long poll
+type PollingQueue interface {
+	Queue
+	// Poll receives up to maxMessages messages, waiting at most wait
+	Poll(ctx context.Context, maxMessages int, wait time.Duration) ([]queue.Message, error)
+}
+
+// DeadLetterQueue defines dead-letter inspection and redrive operations
+type DeadLetterQueue interface {
+	PeekDeadLetters(ctx context.Context, maxMessages int) ([]queue.DeadLetter, error)
+	RedriveDeadLetters(ctx context.Context, messageIDs []string) ([]queue.DeadLetter, error)
 }
 
-// JobProcessor defines job processing operations
+// JobProcessor defines job processing operations for a single job type
 type JobProcessor interface {
-	ProcessJob(job *models.Job) (*models.JobResult, error)
+	ProcessJob(ctx context.Context, job *models.Job) (*models.JobResult, error)
 }
\ No newline at end of file
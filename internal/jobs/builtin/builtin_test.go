@@ -0,0 +1,119 @@
+package builtin
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+func TestRegister(t *testing.T) {
+	registry := jobs.NewRegistry()
+
+	err := Register(registry, nil, nil, slog.Default())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		TypeBatchImport,
+		TypeCleanup,
+		TypeDataAggregation,
+		TypeDataProcessing,
+		TypeHealthReport,
+	}, registry.Types())
+
+	// Registering twice conflicts
+	assert.ErrorIs(t, Register(registry, nil, nil, slog.Default()), jobs.ErrDuplicateHandler)
+}
+
+func TestRegisterSchemas(t *testing.T) {
+	registry := jobs.NewRegistry()
+	assert.NoError(t, RegisterSchemas(registry))
+
+	tests := []struct {
+		jobType string
+		data    string
+		valid   bool
+	}{
+		{TypeCleanup, `"Remove completed jobs older than 7 days"`, true},
+		{TypeCleanup, `{"days": 7}`, false},
+		{TypeBatchImport, `"record1,record2"`, true},
+		{TypeBatchImport, `[{"id": 1}, {"id": 2}]`, true},
+		{TypeBatchImport, `[]`, false},
+		{TypeDataProcessing, `{"rows": [1, 2, 3]}`, true},
+		{TypeDataProcessing, `""`, false},
+		{TypeDataProcessing, `42`, false},
+	}
+	for _, tt := range tests {
+		err := registry.Validate(tt.jobType, []byte(tt.data))
+		if tt.valid {
+			assert.NoError(t, err, "%s %s", tt.jobType, tt.data)
+		} else {
+			assert.ErrorIs(t, err, jobs.ErrInvalidData, "%s %s", tt.jobType, tt.data)
+		}
+	}
+}
+
+func TestHandlers_DataProcessing(t *testing.T) {
+	h := New(nil, nil, slog.Default())
+
+	job := &models.Job{
+		ID:     uuid.New(),
+		Status: models.JobStatusProcessing,
+		Type:   TypeDataProcessing,
+		Data:   models.JSONString("sample data to process"),
+	}
+
+	result, err := h.DataProcessing(context.Background(), job)
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, len(job.Data), result.InputCount)
+	assert.Contains(t, result.Message, "Data processed successfully")
+}
+
+func TestHandlers_BatchImport(t *testing.T) {
+	h := New(nil, nil, slog.Default())
+
+	job := &models.Job{
+		ID:     uuid.New(),
+		Status: models.JobStatusProcessing,
+		Type:   TypeBatchImport,
+		Data:   models.JSONString("record1,record2,record3,record4,record5"),
+	}
+
+	result, err := h.BatchImport(context.Background(), job)
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Contains(t, result.Message, "Batch import completed")
+}
+
+func TestHandlers_DataProcessing_Cancelled(t *testing.T) {
+	h := New(nil, nil, slog.Default())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	job := &models.Job{ID: uuid.New(), Type: TypeDataProcessing, Data: models.JSONString("long enough input to take a while")}
+	result, err := h.DataProcessing(ctx, job)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+}
+
+func TestRecordStatusCounts(t *testing.T) {
+	total := recordStatusCounts(map[models.JobStatus]int64{
+		models.JobStatusPending:   2,
+		models.JobStatusScheduled: 3,
+		models.JobStatusRetrying:  1,
+	})
+
+	assert.Equal(t, int64(6), total)
+	assert.Equal(t, 3.0, testutil.ToFloat64(metrics.JobsByStatus.WithLabelValues(string(models.JobStatusScheduled))))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.JobsByStatus.WithLabelValues(string(models.JobStatusRetrying))))
+	// Statuses with no jobs are reported as zero rather than left unset
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.JobsByStatus.WithLabelValues(string(models.JobStatusCancelled))))
+}
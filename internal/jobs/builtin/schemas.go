@@ -0,0 +1,26 @@
+package builtin
+
+import (
+	"embed"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// RegisterSchemas adds the data schemas of the built-in job types to the
+// registry. The API calls it on its own, since it validates jobs without
+// running them.
+func RegisterSchemas(r *jobs.Registry) error {
+	for _, jobType := range []string{TypeCleanup, TypeHealthReport, TypeDataAggregation, TypeBatchImport, TypeDataProcessing} {
+		schema, err := schemaFiles.ReadFile("schemas/" + jobType + ".json")
+		if err != nil {
+			return err
+		}
+		if err := r.RegisterSchema(jobType, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+var (
+	ErrNoHandler        = errors.New("no handler registered")
+	ErrDuplicateHandler = errors.New("handler already registered")
+	ErrInvalidJobType   = errors.New("job type cannot be empty")
+)
+
+// Handler processes jobs of a single registered type
+type Handler = interfaces.JobProcessor
+
+// HandlerFunc adapts a plain function to Handler
+type HandlerFunc func(ctx context.Context, job *models.Job) (*models.JobResult, error)
+
+func (f HandlerFunc) ProcessJob(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+	return f(ctx, job)
+}
+
+// Registry maps job type names to their handlers and data schemas
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	schemas  map[string]*jsonschema.Schema
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]Handler),
+		schemas:  make(map[string]*jsonschema.Schema),
+	}
+}
+
+// Register adds a handler for jobType. Registering the same type twice is an
+// error so two teams can't silently shadow each other.
+func (r *Registry) Register(jobType string, handler Handler) error {
+	if jobType == "" {
+		return ErrInvalidJobType
+	}
+	if handler == nil {
+		return fmt.Errorf("handler for %q cannot be nil", jobType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.handlers[jobType]; exists {
+		return fmt.Errorf("%w: %q", ErrDuplicateHandler, jobType)
+	}
+	r.handlers[jobType] = handler
+	return nil
+}
+
+// Lookup returns the handler for jobType, wrapping ErrNoHandler if none is registered
+func (r *Registry) Lookup(jobType string) (Handler, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handler, ok := r.handlers[jobType]
+	if !ok {
+		return nil, fmt.Errorf("%w for job type %q", ErrNoHandler, jobType)
+	}
+	return handler, nil
+}
+
+// Types returns the registered job types in sorted order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.handlers))
+	for jobType := range r.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Default is the registry used by the worker binary. Packages can add their
+// handlers from init with Register.
+var Default = NewRegistry()
+
+// Register adds a handler to the Default registry and panics on conflict,
+// since it is meant to be called from init.
+func Register(jobType string, handler Handler) {
+	if err := Default.Register(jobType, handler); err != nil {
+		panic(err)
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying; the job fails immediately
+// regardless of its remaining attempts.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err should skip retries
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe) || errors.Is(err, ErrNoHandler)
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+func noop(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+	return &models.JobResult{Message: job.Type}, nil
+}
+
+func TestRegistry_RegisterAndLookup(t *testing.T) {
+	r := NewRegistry()
+
+	assert.NoError(t, r.Register("email", HandlerFunc(noop)))
+	assert.ErrorIs(t, r.Register("email", HandlerFunc(noop)), ErrDuplicateHandler)
+	assert.ErrorIs(t, r.Register("", HandlerFunc(noop)), ErrInvalidJobType)
+	assert.Error(t, r.Register("report", nil))
+
+	handler, err := r.Lookup("email")
+	assert.NoError(t, err)
+	result, err := handler.ProcessJob(context.Background(), &models.Job{Type: "email"})
+	assert.NoError(t, err)
+	assert.Equal(t, "email", result.Message)
+
+	_, err = r.Lookup("missing")
+	assert.ErrorIs(t, err, ErrNoHandler)
+
+	assert.Equal(t, []string{"email"}, r.Types())
+}
+
+func TestRegister_PanicsOnDuplicate(t *testing.T) {
+	saved := Default
+	Default = NewRegistry()
+	defer func() { Default = saved }()
+
+	Register("email", HandlerFunc(noop))
+	assert.Panics(t, func() { Register("email", HandlerFunc(noop)) })
+}
+
+func TestIsPermanent(t *testing.T) {
+	assert.False(t, IsPermanent(errors.New("timeout")))
+	assert.True(t, IsPermanent(Permanent(errors.New("bad input"))))
+	assert.True(t, IsPermanent(fmt.Errorf("wrapped: %w", Permanent(errors.New("bad input")))))
+	assert.Nil(t, Permanent(nil))
+
+	_, err := NewRegistry().Lookup("missing")
+	assert.True(t, IsPermanent(err))
+}
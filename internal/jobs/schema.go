@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+var (
+	ErrInvalidData     = errors.New("job data does not match its schema")
+	ErrDuplicateSchema = errors.New("schema already registered")
+)
+
+// SchemaError lists where job data breaks its type's schema
+type SchemaError struct {
+	JobType string
+	// Fields maps a location such as data.items.0 to what is wrong there
+	Fields map[string]string
+}
+
+func (e *SchemaError) Error() string {
+	locations := make([]string, 0, len(e.Fields))
+	for location, msg := range e.Fields {
+		locations = append(locations, location+": "+msg)
+	}
+	sort.Strings(locations)
+	return fmt.Sprintf("%s for job type %q: %s", ErrInvalidData, e.JobType, strings.Join(locations, "; "))
+}
+
+func (e *SchemaError) Unwrap() error { return ErrInvalidData }
+
+// FieldErrors lets middleware.ValidationError report each location
+func (e *SchemaError) FieldErrors() map[string]string { return e.Fields }
+
+// RegisterSchema declares the JSON Schema that data of jobType must match.
+// Types without a schema accept any JSON document.
+func (r *Registry) RegisterSchema(jobType string, schema []byte) error {
+	if jobType == "" {
+		return ErrInvalidJobType
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("failed to parse schema for %q: %w", jobType, err)
+	}
+	url := "job-types/" + jobType + ".json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		return fmt.Errorf("failed to load schema for %q: %w", jobType, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return fmt.Errorf("failed to compile schema for %q: %w", jobType, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.schemas[jobType]; exists {
+		return fmt.Errorf("%w: %q", ErrDuplicateSchema, jobType)
+	}
+	r.schemas[jobType] = compiled
+	return nil
+}
+
+// Validate checks data against jobType's schema, returning a *SchemaError
+// if it doesn't match
+func (r *Registry) Validate(jobType string, data []byte) error {
+	r.mu.RLock()
+	schema, ok := r.schemas[jobType]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse job data: %w", err)
+	}
+	err = schema.Validate(doc)
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	fields := make(map[string]string)
+	for _, unit := range ve.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		location := "data" + strings.ReplaceAll(unit.InstanceLocation, "/", ".")
+		if msg, exists := fields[location]; exists {
+			fields[location] = msg + "; " + unit.Error.String()
+		} else {
+			fields[location] = unit.Error.String()
+		}
+	}
+	return &SchemaError{JobType: jobType, Fields: fields}
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const emailSchema = `{
+	"type": "object",
+	"required": ["to", "subject"],
+	"properties": {
+		"to": {"type": "string", "format": "email"},
+		"subject": {"type": "string", "maxLength": 10},
+		"cc": {"type": "array", "items": {"type": "string"}}
+	}
+}`
+
+func TestRegistry_Validate(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.RegisterSchema("email", []byte(emailSchema)))
+	assert.ErrorIs(t, r.RegisterSchema("email", []byte(emailSchema)), ErrDuplicateSchema)
+	assert.ErrorIs(t, r.RegisterSchema("", []byte(emailSchema)), ErrInvalidJobType)
+	assert.Error(t, r.RegisterSchema("broken", []byte(`{"type": 5}`)))
+	assert.Error(t, r.RegisterSchema("broken", []byte(`not json`)))
+
+	tests := []struct {
+		name       string
+		jobType    string
+		data       string
+		wantFields []string
+	}{
+		{"valid", "email", `{"to": "a@example.com", "subject": "hi"}`, nil},
+		{"no schema", "report", `[1, 2, 3]`, nil},
+		{"missing fields", "email", `{}`, []string{"data"}},
+		{"nested fields", "email", `{"to": "a@example.com", "subject": "far too long", "cc": ["b", 2]}`, []string{"data.subject", "data.cc.1"}},
+		{"wrong type", "email", `"just text"`, []string{"data"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Validate(tt.jobType, []byte(tt.data))
+			if tt.wantFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrInvalidData)
+			var schemaErr *SchemaError
+			require.ErrorAs(t, err, &schemaErr)
+			assert.Equal(t, tt.jobType, schemaErr.JobType)
+			assert.Len(t, schemaErr.FieldErrors(), len(tt.wantFields))
+			for _, field := range tt.wantFields {
+				assert.Contains(t, schemaErr.FieldErrors(), field)
+			}
+		})
+	}
+}
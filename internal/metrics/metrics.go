@@ -0,0 +1,125 @@
+// Package metrics defines the Prometheus metrics exported by the API and the
+// worker, both on the default registry.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// jobDurationBuckets spans quick handlers to ones near the default max runtime
+var jobDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 1800}
+
+var (
+	// HTTPRequestDuration is API request latency by route template
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "API request latency by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// JobsCreated counts new jobs, whether submitted, retried by hand or
+	// fired by a schedule
+	JobsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_created_total",
+		Help: "Jobs created, by type.",
+	}, []string{"type"})
+
+	// JobsProcessed counts processing attempts by the status they left the
+	// job in: completed, failed, retrying or cancelled
+	JobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_processed_total",
+		Help: "Job processing attempts, by type and resulting status.",
+	}, []string{"type", "status"})
+
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_processing_duration_seconds",
+		Help:    "Time spent running job handlers, by type and resulting status.",
+		Buckets: jobDurationBuckets,
+	}, []string{"type", "status"})
+
+	// QueueLatency is how long messages waited in the queue before a worker
+	// received them, delivery delays included
+	QueueLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "queue_receive_latency_seconds",
+		Help:    "Time from sending a message to a worker receiving it, by priority.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+	}, []string{"priority"})
+
+	JobsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "worker_jobs_in_flight",
+		Help: "Messages the worker has received and not yet finished with.",
+	})
+
+	// JobsByStatus is the number of jobs in each status as of the latest
+	// health-report job
+	JobsByStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "jobs_by_status",
+		Help: "Jobs in each status, as counted by the latest health report.",
+	}, []string{"status"})
+
+	// OutboxPublished counts outbox messages the relay sent to the queue
+	OutboxPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_messages_published_total",
+		Help: "Outbox messages published to the queue.",
+	})
+
+	// OutboxFailed counts failed publish attempts, each retried with backoff
+	OutboxFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_publish_failures_total",
+		Help: "Failed attempts to publish outbox messages to the queue.",
+	})
+
+	// OutboxBacklog is the number of undelivered outbox messages as last
+	// counted by the relay
+	OutboxBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_backlog",
+		Help: "Outbox messages not yet published, as last counted by the relay.",
+	})
+
+	// WebhookDeliveries counts webhook delivery attempts by the status they
+	// left the delivery in: delivered, pending (to be retried) or failed
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Webhook delivery attempts, by resulting status.",
+	}, []string{"status"})
+)
+
+// RegisterDB exports the connection pool stats of db, labelled with name
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the default registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve runs a listener for /metrics on addr until ctx is cancelled
+func Serve(ctx context.Context, addr string, logger *slog.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("Metrics listener started", "addr", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
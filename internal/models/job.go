@@ -10,15 +10,60 @@ import (
 type JobStatus string
 
 const (
-	JobStatusPending   JobStatus = "pending"
+	JobStatusPending    JobStatus = "pending"
 	JobStatusProcessing JobStatus = "processing"
-	JobStatusCompleted JobStatus = "completed"
-	JobStatusFailed    JobStatus = "failed"
+	JobStatusCompleted  JobStatus = "completed"
+	JobStatusFailed     JobStatus = "failed"
+	JobStatusRetrying   JobStatus = "retrying"
+	JobStatusCancelled  JobStatus = "cancelled"
+	// JobStatusScheduled jobs are held in the database until near their RunAt
+	JobStatusScheduled JobStatus = "scheduled"
 )
 
+// JobStatuses lists every status a job can be in
+var JobStatuses = []JobStatus{
+	JobStatusPending, JobStatusScheduled, JobStatusProcessing, JobStatusRetrying,
+	JobStatusCompleted, JobStatusFailed, JobStatusCancelled,
+}
+
+// JobPriority picks the queue a job is sent to. Workers poll higher
+// priorities more often, but every priority gets a share.
+type JobPriority string
+
+const (
+	JobPriorityHigh   JobPriority = "high"
+	JobPriorityNormal JobPriority = "normal"
+	JobPriorityLow    JobPriority = "low"
+)
+
+// JobPriorities lists the priorities from highest to lowest
+var JobPriorities = []JobPriority{JobPriorityHigh, JobPriorityNormal, JobPriorityLow}
+
+// DefaultMaxAttempts is used when a job doesn't set MaxAttempts
+const DefaultMaxAttempts = 3
+
+// MaxJobDelay is how far ahead a job may be scheduled
+const MaxJobDelay = 365 * 24 * time.Hour
+
 type JobPayload struct {
 	Type string `json:"type" validate:"required,min=1,max=100"`
-	Data string `json:"data" validate:"required,min=1,max=10000"`
+	// Data is any JSON document, checked against the type's schema if it has one
+	Data JSON `json:"data" validate:"required,max=65536"`
+
+	MaxAttempts int         `json:"max_attempts,omitempty" validate:"omitempty,min=1,max=20"`
+	Priority    JobPriority `json:"priority,omitempty" validate:"omitempty,oneof=high normal low"`
+
+	// RunAt or DelaySeconds postpone the job; they can't both be set
+	RunAt        *time.Time `json:"run_at,omitempty"`
+	DelaySeconds int        `json:"delay_seconds,omitempty" validate:"omitempty,min=1,max=31536000"`
+
+	// CallbackURL is sent a signed notification when the job finishes
+	CallbackURL string `json:"callback_url,omitempty" validate:"omitempty,max=2048,http_url"`
+}
+
+// RetryPayload is the optional body of a manual retry request
+type RetryPayload struct {
+	RetriedBy string `json:"retried_by" validate:"omitempty,max=255"`
 }
 
 type JobResult struct {
@@ -27,24 +72,109 @@ type JobResult struct {
 	Message     string    `json:"message"`
 }
 
+// JobAttempt records the outcome of a single processing attempt
+type JobAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
 type Job struct {
-	ID        uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primary_key" json:"id"`
-	Status    JobStatus   `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
-	Type      string      `gorm:"type:varchar(100);not null" json:"type"`
-	Data      string      `gorm:"type:text;not null" json:"data"`
-	Result    *JobResult  `gorm:"serializer:json" json:"result,omitempty"`
-	Error     string      `gorm:"type:text" json:"error,omitempty"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
+	ID             uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primary_key;index:idx_jobs_created_at_id,priority:2" json:"id"`
+	Status         JobStatus    `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Type           string       `gorm:"type:varchar(100);not null" json:"type"`
+	Priority       JobPriority  `gorm:"type:varchar(16);not null;default:'normal'" json:"priority"`
+	Data           JSON         `gorm:"type:jsonb;not null;index:idx_jobs_data,type:gin" json:"data"`
+	Result         *JobResult   `gorm:"serializer:json" json:"result,omitempty"`
+	Error          string       `gorm:"type:text" json:"error,omitempty"`
+	Attempts       int          `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts    int          `gorm:"not null;default:3" json:"max_attempts"`
+	AttemptHistory []JobAttempt `gorm:"serializer:json" json:"attempt_history,omitempty"`
+	WorkerID       string       `gorm:"type:varchar(255)" json:"worker_id,omitempty"`
+	LeaseExpiresAt *time.Time   `json:"lease_expires_at,omitempty"`
+	// CancelRequestedAt is set when a running job is asked to stop
+	CancelRequestedAt *time.Time `json:"cancel_requested_at,omitempty"`
+	// ParentID links a manual re-run to the job it was retried from
+	ParentID  *uuid.UUID `gorm:"type:uuid;index" json:"parent_id,omitempty"`
+	RetriedBy string     `gorm:"type:varchar(255)" json:"retried_by,omitempty"`
+	RetriedAt *time.Time `json:"retried_at,omitempty"`
+	// RunAt is when a delayed job becomes due
+	RunAt     *time.Time `json:"run_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index:idx_jobs_created_at_id,priority:1" json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	// TraceContext is the trace context of the request that created the
+	// job, handed on to the queue message
+	TraceContext map[string]string `gorm:"serializer:json;type:jsonb" json:"-"`
+
+	CallbackURL string `gorm:"type:text" json:"callback_url,omitempty"`
 }
 
 func (Job) TableName() string {
 	return "jobs"
 }
 
+// CanRetry reports whether the job has attempts left
+func (j *Job) CanRetry() bool {
+	maxAttempts := j.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	return j.Attempts < maxAttempts
+}
+
+// IsFinished reports whether the job reached a final status and must not run again
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+// Rerunnable reports whether an operator may retry the job by hand
+func (j *Job) Rerunnable() bool {
+	return j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+// Rerun returns a new pending job with the same work as j, linked to it
+// through ParentID
+func (j *Job) Rerun(retriedBy string, now time.Time) *Job {
+	parentID := j.ID
+	return &Job{
+		Status:      JobStatusPending,
+		Type:        j.Type,
+		Data:        append(JSON(nil), j.Data...),
+		MaxAttempts: j.MaxAttempts,
+		Priority:    j.Priority,
+		CallbackURL: j.CallbackURL,
+		ParentID:    &parentID,
+		RetriedBy:   retriedBy,
+		RetriedAt:   &now,
+	}
+}
+
+// Claimable reports whether a worker may start the job at now: it is
+// waiting to run, or its last worker's lease has expired
+func (j *Job) Claimable(now time.Time) bool {
+	switch j.Status {
+	case JobStatusPending, JobStatusRetrying:
+		return true
+	case JobStatusProcessing:
+		return j.LeaseExpiresAt == nil || j.LeaseExpiresAt.Before(now)
+	}
+	return false
+}
+
+// Redrivable reports whether a job whose message was dead-lettered may be
+// queued again: it failed, or it is claimable at now
+func (j *Job) Redrivable(now time.Time) bool {
+	return j.Status == JobStatusFailed || j.Claimable(now)
+}
+
 func (j *Job) BeforeCreate(tx *gorm.DB) error {
 	if j.ID == uuid.Nil {
 		j.ID = uuid.New()
 	}
+	if j.Priority == "" {
+		j.Priority = JobPriorityNormal
+	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobEvent is a change of a job's status, as announced by the database and
+// streamed to API clients
+type JobEvent struct {
+	JobID  uuid.UUID `json:"job_id"`
+	Type   string    `json:"type"`
+	Status JobStatus `json:"status"`
+	// PreviousStatus is empty for a newly created job
+	PreviousStatus JobStatus `json:"previous_status,omitempty"`
+	Attempts       int       `json:"attempts"`
+	At             time.Time `json:"at"`
+}
+
+// NewJobEvent describes job's current status, e.g. to open a stream of its
+// changes
+func NewJobEvent(job *Job) JobEvent {
+	return JobEvent{
+		JobID:    job.ID,
+		Type:     job.Type,
+		Status:   job.Status,
+		Attempts: job.Attempts,
+		At:       job.UpdatedAt,
+	}
+}
+
+// Final reports whether the event leaves the job in a final status
+func (e JobEvent) Final() bool {
+	return e.Status == JobStatusCompleted || e.Status == JobStatusFailed || e.Status == JobStatusCancelled
+}
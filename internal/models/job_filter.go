@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// JobOrder is the direction jobs are listed in by (created_at, id)
+type JobOrder string
+
+const (
+	JobOrderNewest JobOrder = "desc"
+	JobOrderOldest JobOrder = "asc"
+)
+
+const (
+	DefaultJobListLimit = 100
+	MaxJobListLimit     = 1000
+)
+
+// JobFilter selects a page of jobs. Zero fields don't filter.
+type JobFilter struct {
+	Statuses      []JobStatus
+	Types         []string
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	// Search matches case-insensitively anywhere in Data or Error
+	Search string
+
+	Order JobOrder
+	Limit int
+	// Cursor is the NextCursor of the previous page
+	Cursor string
+	// IncludeTotal counts all jobs matching the filter, ignoring the cursor
+	IncludeTotal bool
+}
+
+// JobPage is one page of ListJobs results
+type JobPage struct {
+	Jobs       []Job  `json:"jobs"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// Normalized returns the filter with its order and limit defaulted and the
+// limit capped at MaxJobListLimit
+func (f JobFilter) Normalized() JobFilter {
+	if f.Order != JobOrderOldest {
+		f.Order = JobOrderNewest
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultJobListLimit
+	}
+	if f.Limit > MaxJobListLimit {
+		f.Limit = MaxJobListLimit
+	}
+	return f
+}
@@ -11,7 +11,7 @@ func TestJob_BeforeCreate(t *testing.T) {
 	job := &Job{
 		Status:    JobStatusPending,
 		Type:      "test",
-		Data:      "test data",
+		Data:      JSONString("test data"),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -36,13 +36,73 @@ func TestJobStatus_Values(t *testing.T) {
 		JobStatusProcessing,
 		JobStatusCompleted,
 		JobStatusFailed,
+		JobStatusRetrying,
+		JobStatusCancelled,
+		JobStatusScheduled,
 	}
 
-	expected := []string{"pending", "processing", "completed", "failed"}
+	expected := []string{"pending", "processing", "completed", "failed", "retrying", "cancelled", "scheduled"}
 
 	for i, status := range statuses {
 		if string(status) != expected[i] {
 			t.Errorf("expected %s, got %s", expected[i], string(status))
 		}
 	}
-}
\ No newline at end of file
+}
+func TestJob_CanRetry(t *testing.T) {
+	tests := []struct {
+		name        string
+		attempts    int
+		maxAttempts int
+		want        bool
+	}{
+		{name: "attempts left", attempts: 1, maxAttempts: 3, want: true},
+		{name: "exhausted", attempts: 3, maxAttempts: 3, want: false},
+		{name: "single attempt", attempts: 1, maxAttempts: 1, want: false},
+		{name: "unset max uses default", attempts: DefaultMaxAttempts - 1, maxAttempts: 0, want: true},
+		{name: "unset max exhausted", attempts: DefaultMaxAttempts, maxAttempts: 0, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Attempts: tt.attempts, MaxAttempts: tt.maxAttempts}
+			if got := job.CanRetry(); got != tt.want {
+				t.Errorf("CanRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJob_Claimable(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		job       Job
+		claimable bool
+		finished  bool
+	}{
+		{"pending", Job{Status: JobStatusPending}, true, false},
+		{"retrying", Job{Status: JobStatusRetrying}, true, false},
+		{"processing with live lease", Job{Status: JobStatusProcessing, LeaseExpiresAt: &future}, false, false},
+		{"processing with expired lease", Job{Status: JobStatusProcessing, LeaseExpiresAt: &past}, true, false},
+		{"processing without lease", Job{Status: JobStatusProcessing}, true, false},
+		{"completed", Job{Status: JobStatusCompleted}, false, true},
+		{"failed", Job{Status: JobStatusFailed}, false, true},
+		{"cancelled", Job{Status: JobStatusCancelled}, false, true},
+		{"scheduled", Job{Status: JobStatusScheduled}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.job.Claimable(now); got != tt.claimable {
+				t.Errorf("Claimable() = %v, want %v", got, tt.claimable)
+			}
+			if got := tt.job.IsFinished(); got != tt.finished {
+				t.Errorf("IsFinished() = %v, want %v", got, tt.finished)
+			}
+		})
+	}
+}
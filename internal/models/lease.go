@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Lease records which instance holds a named singleton role, such as the
+// scheduler, until ExpiresAt. Holders renew it well before then; once it
+// lapses any instance may take it over.
+type Lease struct {
+	Name       string    `gorm:"type:varchar(100);primary_key" json:"name"`
+	Holder     string    `gorm:"type:varchar(255);not null" json:"holder"`
+	AcquiredAt time.Time `gorm:"not null" json:"acquired_at"`
+	RenewedAt  time.Time `gorm:"not null" json:"renewed_at"`
+	ExpiresAt  time.Time `gorm:"not null" json:"expires_at"`
+}
+
+func (Lease) TableName() string {
+	return "leases"
+}
+
+// Active reports whether the lease is still held at now
+func (l *Lease) Active(now time.Time) bool {
+	return now.Before(l.ExpiresAt)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OutboxMessage is a queue message recorded in the same transaction as its job
+// and published to the queue by the outbox relay. RunAt, if set, delays
+// delivery until the job is due, Priority picks the queue and TraceContext
+// continues the job's trace in the message.
+type OutboxMessage struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	JobID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"job_id"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	DeliveredAt   *time.Time `gorm:"index" json:"delivered_at,omitempty"`
+	RunAt         *time.Time `json:"run_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	Priority JobPriority `gorm:"type:varchar(16);not null;default:'normal'" json:"priority"`
+
+	TraceContext map[string]string `gorm:"serializer:json;type:jsonb" json:"-"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "job_outbox"
+}
+
+func (m *OutboxMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QueueMessage is a message stored by the Postgres queue backend
+type QueueMessage struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	Queue         string     `gorm:"type:varchar(100);not null;index:idx_queue_messages_visible,priority:1" json:"queue"`
+	Body          string     `gorm:"type:text;not null" json:"body"`
+	ReceiptHandle *uuid.UUID `gorm:"type:uuid;uniqueIndex" json:"-"`
+	ReceiveCount  int        `gorm:"not null;default:0" json:"receive_count"`
+	VisibleAt     time.Time  `gorm:"not null;index:idx_queue_messages_visible,priority:2" json:"visible_at"`
+	DeadAt        *time.Time `gorm:"index" json:"dead_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	Attributes map[string]string `gorm:"serializer:json;type:jsonb" json:"attributes,omitempty"`
+}
+
+func (QueueMessage) TableName() string {
+	return "queue_messages"
+}
+
+func (m *QueueMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MisfirePolicy says what to do about firings missed while no scheduler was
+// running
+type MisfirePolicy string
+
+const (
+	// MisfireSkip drops missed firings
+	MisfireSkip MisfirePolicy = "skip"
+	// MisfireRunOnce fires once for the latest missed time
+	MisfireRunOnce MisfirePolicy = "run_once"
+	// MisfireRunAll fires for every missed time, oldest first
+	MisfireRunAll MisfirePolicy = "run_all"
+)
+
+// DefaultCatchUpWindow is how far back missed firings are caught up unless
+// a schedule says otherwise
+const DefaultCatchUpWindow = 24 * time.Hour
+
+// Schedule creates a job of JobType each time its cron expression fires
+type Schedule struct {
+	ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	Name     string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	CronExpr string    `gorm:"column:cron_expr;type:varchar(100);not null" json:"cron"`
+	JobType  string    `gorm:"type:varchar(100);not null" json:"job_type"`
+	// PayloadTemplate is a text/template producing the job's JSON data
+	PayloadTemplate string `gorm:"type:text;not null" json:"payload_template"`
+	Timezone        string `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	Enabled         bool   `gorm:"not null" json:"enabled"`
+
+	MisfirePolicy MisfirePolicy `gorm:"type:varchar(16);not null" json:"misfire_policy"`
+	// CatchUpWindowSeconds bounds how far back missed firings are caught up
+	CatchUpWindowSeconds int `gorm:"not null" json:"catch_up_window_seconds"`
+
+	// Priority is given to the jobs the schedule creates
+	Priority JobPriority `gorm:"type:varchar(16);not null" json:"priority"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SchedulePayload is the body for creating or replacing a schedule
+type SchedulePayload struct {
+	Name            string `json:"name" validate:"required,min=1,max=100"`
+	Cron            string `json:"cron" validate:"required,max=100"`
+	JobType         string `json:"job_type" validate:"required,min=1,max=100"`
+	PayloadTemplate string `json:"payload_template" validate:"required,max=65536"`
+	Timezone        string `json:"timezone" validate:"omitempty,max=64"`
+	Enabled         *bool  `json:"enabled"`
+
+	MisfirePolicy        MisfirePolicy `json:"misfire_policy" validate:"omitempty,oneof=skip run_once run_all"`
+	CatchUpWindowSeconds int           `json:"catch_up_window_seconds" validate:"omitempty,min=1,max=2592000"`
+
+	Priority JobPriority `json:"priority" validate:"omitempty,oneof=high normal low"`
+}
+
+func (Schedule) TableName() string {
+	return "schedules"
+}
+
+// CatchUpWindow is how far back missed firings are caught up
+func (s *Schedule) CatchUpWindow() time.Duration {
+	if s.CatchUpWindowSeconds <= 0 {
+		return DefaultCatchUpWindow
+	}
+	return time.Duration(s.CatchUpWindowSeconds) * time.Second
+}
+
+func (s *Schedule) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	s.SetDefaults()
+	return nil
+}
+
+// SetDefaults fills in the misfire settings and priority of a new schedule
+func (s *Schedule) SetDefaults() {
+	if s.MisfirePolicy == "" {
+		s.MisfirePolicy = MisfireSkip
+	}
+	if s.CatchUpWindowSeconds == 0 {
+		s.CatchUpWindowSeconds = int(DefaultCatchUpWindow / time.Second)
+	}
+	if s.Priority == "" {
+		s.Priority = JobPriorityNormal
+	}
+}
+
+// ScheduleRun records one firing of a schedule: when it was planned for, when
+// it actually ran and the job it created, or why it created none
+type ScheduleRun struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	ScheduleID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_schedule_runs_planned" json:"schedule_id"`
+	PlannedAt  time.Time  `gorm:"not null;uniqueIndex:idx_schedule_runs_planned" json:"planned_at"`
+	FiredAt    time.Time  `gorm:"not null" json:"fired_at"`
+	JobID      *uuid.UUID `gorm:"type:uuid" json:"job_id,omitempty"`
+	Error      string     `gorm:"type:text" json:"error,omitempty"`
+	// CatchUp marks firings made up for after the scheduler was down
+	CatchUp   bool      `gorm:"not null" json:"catch_up"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ScheduleRun) TableName() string {
+	return "schedule_runs"
+}
+
+func (r *ScheduleRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
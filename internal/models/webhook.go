@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookStatus string
+
+const (
+	WebhookStatusPending   WebhookStatus = "pending"
+	WebhookStatusDelivered WebhookStatus = "delivered"
+	// WebhookStatusFailed deliveries ran out of attempts; they can be replayed
+	WebhookStatusFailed WebhookStatus = "failed"
+)
+
+// WebhookEvent is the JSON body POSTed to a job's callback URL when it
+// finishes
+type WebhookEvent struct {
+	// Event is "job." followed by the job's final status, e.g. job.completed
+	Event      string     `json:"event"`
+	JobID      uuid.UUID  `json:"job_id"`
+	Type       string     `json:"type"`
+	Status     JobStatus  `json:"status"`
+	Result     *JobResult `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Attempts   int        `json:"attempts"`
+	FinishedAt time.Time  `json:"finished_at"`
+}
+
+// WebhookDelivery is a notification to send, or sent, to a job's callback
+// URL, and the log of attempts to send it. Payload is fixed when the job
+// finishes, so retries and replays send the same body.
+type WebhookDelivery struct {
+	ID             uuid.UUID     `gorm:"type:uuid;default:gen_random_uuid();primary_key" json:"id"`
+	JobID          uuid.UUID     `gorm:"type:uuid;not null;index" json:"job_id"`
+	URL            string        `gorm:"type:text;not null" json:"url"`
+	Event          string        `gorm:"type:varchar(50);not null" json:"event"`
+	Payload        JSON          `gorm:"type:jsonb;not null" json:"payload"`
+	Status         WebhookStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Attempts       int           `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time     `gorm:"not null" json:"next_attempt_at"`
+	LastError      string        `gorm:"type:text" json:"last_error,omitempty"`
+	ResponseStatus int           `json:"response_status,omitempty"`
+	DeliveredAt    *time.Time    `json:"delivered_at,omitempty"`
+	// ReplayOf links a replay to the delivery it repeats
+	ReplayOf  *uuid.UUID `gorm:"type:uuid" json:"replay_of,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.NextAttemptAt.IsZero() {
+		d.NextAttemptAt = time.Now()
+	}
+	return nil
+}
+
+// NewWebhookDelivery returns the delivery announcing that job finished, or
+// nil if the job has no callback URL
+func NewWebhookDelivery(job *Job, now time.Time) (*WebhookDelivery, error) {
+	if job.CallbackURL == "" {
+		return nil, nil
+	}
+
+	event := WebhookEvent{
+		Event:      "job." + string(job.Status),
+		JobID:      job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Result:     job.Result,
+		Error:      job.Error,
+		Attempts:   job.Attempts,
+		FinishedAt: now,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookDelivery{
+		ID:            uuid.New(),
+		JobID:         job.ID,
+		URL:           job.CallbackURL,
+		Event:         event.Event,
+		Payload:       payload,
+		Status:        WebhookStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+// Replay returns a new pending delivery of the same notification, linked to
+// d through ReplayOf
+func (d *WebhookDelivery) Replay(now time.Time) *WebhookDelivery {
+	id := d.ID
+	return &WebhookDelivery{
+		ID:            uuid.New(),
+		JobID:         d.JobID,
+		URL:           d.URL,
+		Event:         d.Event,
+		Payload:       append(JSON(nil), d.Payload...),
+		Status:        WebhookStatusPending,
+		NextAttemptAt: now,
+		ReplayOf:      &id,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// WebhookFilter selects deliveries to list, newest first
+type WebhookFilter struct {
+	JobID  *uuid.UUID
+	Status WebhookStatus
+	Limit  int
+}
@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+)
+
+const (
+	// claimLease hides claimed rows from other relays while they are published
+	claimLease = 30 * time.Second
+
+	baseBackoff = time.Second
+	maxBackoff  = 5 * time.Minute
+
+	// backlogInterval is how often the outbox_backlog gauge is recounted
+	backlogInterval = 15 * time.Second
+)
+
+// Relay publishes undelivered outbox messages to the queue
+type Relay struct {
+	store     interfaces.Outbox
+	queue     interfaces.Queue
+	logger    *slog.Logger
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRelay(store interfaces.Outbox, queue interfaces.Queue, logger *slog.Logger, interval time.Duration, batchSize int) *Relay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	return &Relay{
+		store:     store,
+		queue:     queue,
+		logger:    logger,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	r.logger.Info("Outbox relay started")
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	backlogTicker := time.NewTicker(backlogInterval)
+	defer backlogTicker.Stop()
+	r.countBacklog()
+
+	for {
+		// Keep draining while batches come back full
+		for {
+			n, err := r.relayBatch(ctx)
+			if err != nil {
+				r.logger.Error("failed to claim outbox messages", "error", err)
+				break
+			}
+			if n < r.batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay shutting down")
+			return nil
+		case <-backlogTicker.C:
+			r.countBacklog()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	messages, err := r.store.ClaimOutboxMessages(r.batchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range messages {
+		r.publish(ctx, msg)
+	}
+
+	return len(messages), nil
+}
+
+func (r *Relay) publish(ctx context.Context, msg models.OutboxMessage) {
+	// Publishing is part of the trace of the request that created the job;
+	// the queue passes it on in the message
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, msg.TraceContext), "outbox publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("job.id", msg.JobID.String())),
+	)
+	defer span.End()
+
+	opts := []queue.SendOption{queue.WithPriority(msg.Priority)}
+	if msg.RunAt != nil {
+		// Jobs are only queued once within the queue's delay range of run_at
+		opts = append(opts, queue.WithDelay(time.Until(*msg.RunAt)))
+	}
+
+	if err := r.queue.SendMessage(ctx, msg.JobID.String(), opts...); err != nil {
+		metrics.OutboxFailed.Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		next := time.Now().Add(Backoff(msg.Attempts))
+		r.logger.Warn("failed to publish outbox message",
+			"error", err, "job_id", msg.JobID, "attempts", msg.Attempts, "next_attempt_at", next)
+		if err := r.store.MarkOutboxFailed(msg.ID, err.Error(), next); err != nil {
+			r.logger.Error("failed to record outbox failure", "error", err, "outbox_id", msg.ID)
+		}
+		return
+	}
+
+	metrics.OutboxPublished.Inc()
+	// A failure here only causes a duplicate publish once the lease expires
+	if err := r.store.MarkOutboxDelivered(msg.ID); err != nil {
+		r.logger.Error("failed to mark outbox message delivered", "error", err, "outbox_id", msg.ID)
+	}
+}
+
+// countBacklog updates the outbox_backlog gauge
+func (r *Relay) countBacklog() {
+	backlog, err := r.store.CountUndeliveredOutbox()
+	if err != nil {
+		r.logger.Error("failed to count outbox backlog", "error", err)
+		return
+	}
+	metrics.OutboxBacklog.Set(float64(backlog))
+}
+
+// Backoff returns the delay before the next publish attempt, doubling per
+// attempt up to maxBackoff.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,210 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing/tracingtest"
+)
+
+type fakeOutbox struct {
+	mu        sync.Mutex
+	pending   []models.OutboxMessage
+	delivered []uuid.UUID
+	failed    map[uuid.UUID]time.Time
+}
+
+func (f *fakeOutbox) ClaimOutboxMessages(limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if limit > len(f.pending) {
+		limit = len(f.pending)
+	}
+	claimed := f.pending[:limit]
+	f.pending = f.pending[limit:]
+	for i := range claimed {
+		claimed[i].Attempts++
+	}
+	return claimed, nil
+}
+
+func (f *fakeOutbox) MarkOutboxDelivered(id uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delivered = append(f.delivered, id)
+	return nil
+}
+
+func (f *fakeOutbox) MarkOutboxFailed(id uuid.UUID, reason string, nextAttemptAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failed == nil {
+		f.failed = make(map[uuid.UUID]time.Time)
+	}
+	f.failed[id] = nextAttemptAt
+	return nil
+}
+
+func (f *fakeOutbox) CountUndeliveredOutbox() (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.pending)), nil
+}
+
+type mockQueue struct {
+	mock.Mock
+}
+
+func (m *mockQueue) SendMessage(ctx context.Context, jobID string, opts ...queue.SendOption) error {
+	return m.Called(jobID).Error(0)
+}
+
+func (m *mockQueue) ReceiveMessages(ctx context.Context, maxMessages int) ([]queue.Message, error) {
+	return nil, nil
+}
+
+func (m *mockQueue) Ack(ctx context.Context, msg queue.Message) error {
+	return nil
+}
+
+func (m *mockQueue) Nack(ctx context.Context, msg queue.Message, delay time.Duration) error {
+	return nil
+}
+
+func (m *mockQueue) Extend(ctx context.Context, msg queue.Message, timeout time.Duration) error {
+	return nil
+}
+
+func TestRelay_relayBatch(t *testing.T) {
+	ok := models.OutboxMessage{ID: uuid.New(), JobID: uuid.New()}
+	broken := models.OutboxMessage{ID: uuid.New(), JobID: uuid.New()}
+
+	store := &fakeOutbox{pending: []models.OutboxMessage{ok, broken}}
+	q := &mockQueue{}
+	q.On("SendMessage", ok.JobID.String()).Return(nil)
+	q.On("SendMessage", broken.JobID.String()).Return(errors.New("queue unavailable"))
+
+	r := NewRelay(store, q, slog.Default(), time.Second, 10)
+
+	published := testutil.ToFloat64(metrics.OutboxPublished)
+	failed := testutil.ToFloat64(metrics.OutboxFailed)
+	before := time.Now()
+	n, err := r.relayBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	assert.Equal(t, []uuid.UUID{ok.ID}, store.delivered)
+	assert.Contains(t, store.failed, broken.ID)
+	assert.True(t, store.failed[broken.ID].After(before))
+
+	assert.Equal(t, published+1, testutil.ToFloat64(metrics.OutboxPublished))
+	assert.Equal(t, failed+1, testutil.ToFloat64(metrics.OutboxFailed))
+	q.AssertExpectations(t)
+}
+
+func TestRelay_Start_DrainsAndStops(t *testing.T) {
+	store := &fakeOutbox{}
+	for i := 0; i < 5; i++ {
+		store.pending = append(store.pending, models.OutboxMessage{ID: uuid.New(), JobID: uuid.New()})
+	}
+	q := &mockQueue{}
+	q.On("SendMessage", mock.Anything).Return(nil)
+
+	r := NewRelay(store, q, slog.Default(), 10*time.Millisecond, 2)
+	published := testutil.ToFloat64(metrics.OutboxPublished)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, r.Start(ctx))
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.OutboxPublished) == published+5
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestRelay_countBacklog(t *testing.T) {
+	store := &fakeOutbox{pending: make([]models.OutboxMessage, 3)}
+	r := NewRelay(store, &mockQueue{}, slog.Default(), time.Second, 10)
+
+	r.countBacklog()
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.OutboxBacklog))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, Backoff(0))
+	assert.Equal(t, time.Second, Backoff(1))
+	assert.Equal(t, 2*time.Second, Backoff(2))
+	assert.Equal(t, 8*time.Second, Backoff(4))
+	assert.Equal(t, maxBackoff, Backoff(20))
+}
+
+func TestRelay_relayBatch_RunAt(t *testing.T) {
+	later := time.Now().Add(50 * time.Millisecond)
+	store := &fakeOutbox{pending: []models.OutboxMessage{
+		{ID: uuid.New(), JobID: uuid.New(), RunAt: &later},
+	}}
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	r := NewRelay(store, q, slog.Default(), time.Second, 10)
+	_, err := r.relayBatch(context.Background())
+	assert.NoError(t, err)
+
+	// The message is held back until run_at
+	messages, err := q.ReceiveMessages(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+
+	time.Sleep(time.Until(later))
+	messages, err = q.ReceiveMessages(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+}
+
+func TestRelay_relayBatch_TraceContext(t *testing.T) {
+	spans := tracingtest.Record(t)
+
+	ctx, request := tracing.Tracer().Start(context.Background(), "request")
+	request.End()
+	store := &fakeOutbox{pending: []models.OutboxMessage{
+		{ID: uuid.New(), JobID: uuid.New(), TraceContext: tracing.Inject(ctx)},
+	}}
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	r := NewRelay(store, q, slog.Default(), time.Second, 10)
+	_, err := r.relayBatch(context.Background())
+	require.NoError(t, err)
+
+	publish := tracingtest.Find(spans, "outbox publish")
+	require.NotNil(t, publish)
+	assert.Equal(t, request.SpanContext().TraceID(), publish.SpanContext.TraceID())
+	assert.Equal(t, request.SpanContext().SpanID(), publish.Parent.SpanID())
+
+	// The message continues from the publish span
+	messages, err := q.ReceiveMessages(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	carried := trace.SpanContextFromContext(tracing.Extract(context.Background(), messages[0].Attributes))
+	assert.Equal(t, publish.SpanContext.SpanID(), carried.SpanID())
+}
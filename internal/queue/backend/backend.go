@@ -0,0 +1,59 @@
+// Package backend selects the queue implementation from configuration.
+package backend
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
+)
+
+const (
+	SQS      = "sqs"
+	Postgres = "postgres"
+)
+
+// New returns the queue named by cfg.QueueBackend. With more than one
+// priority queue it is a PriorityQueue over them: the Postgres backend always
+// has one per priority, SQS one for each priority given a queue URL.
+func New(cfg *config.Config, db *gorm.DB) (interfaces.Queue, error) {
+	queues := make(map[models.JobPriority]interfaces.PollingQueue)
+	switch cfg.QueueBackend {
+	case SQS, "":
+		client, err := queue.NewSQSClient(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SQS client: %w", err)
+		}
+		queues[models.JobPriorityNormal] = client
+		if cfg.SQSHighQueueURL != "" {
+			queues[models.JobPriorityHigh] = client.ForQueue(cfg.SQSHighQueueURL)
+		}
+		if cfg.SQSLowQueueURL != "" {
+			queues[models.JobPriorityLow] = client.ForQueue(cfg.SQSLowQueueURL)
+		}
+	case Postgres:
+		pq := queue.NewPostgresQueue(db, cfg)
+		queues[models.JobPriorityNormal] = pq
+		queues[models.JobPriorityHigh] = pq.Named(cfg.QueueName + "-high")
+		queues[models.JobPriorityLow] = pq.Named(cfg.QueueName + "-low")
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q", cfg.QueueBackend)
+	}
+
+	if len(queues) == 1 {
+		return queues[models.JobPriorityNormal], nil
+	}
+	return NewPriorityQueue(queues, priorityWeights(cfg.QueuePriorityWeights))
+}
+
+func priorityWeights(configured map[string]int) map[models.JobPriority]int {
+	weights := make(map[models.JobPriority]int, len(configured))
+	for priority, weight := range configured {
+		weights[models.JobPriority(priority)] = weight
+	}
+	return weights
+}
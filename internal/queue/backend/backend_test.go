@@ -0,0 +1,30 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
+)
+
+func TestNew(t *testing.T) {
+	q, err := New(&config.Config{QueueBackend: Postgres, QueueName: "jobs"}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &PriorityQueue{}, q)
+	assert.Implements(t, (*interfaces.DeadLetterQueue)(nil), q)
+
+	q, err = New(&config.Config{QueueBackend: SQS, AWSRegion: "us-east-2"}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &queue.SQSClient{}, q)
+	assert.Implements(t, (*interfaces.DeadLetterQueue)(nil), q)
+
+	q, err = New(&config.Config{QueueBackend: SQS, AWSRegion: "us-east-2", SQSLowQueueURL: "http://localhost:4566/jobs-low"}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &PriorityQueue{}, q)
+
+	_, err = New(&config.Config{QueueBackend: "kafka"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,228 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+)
+
+// DefaultPriorityWeights is how often each priority's queue is polled first,
+// relative to the others, unless configured otherwise
+var DefaultPriorityWeights = map[models.JobPriority]int{
+	models.JobPriorityHigh:   6,
+	models.JobPriorityNormal: 3,
+	models.JobPriorityLow:    1,
+}
+
+// laneWait is how long a receive long-polls each queue in turn. It is the
+// shortest long poll SQS has, so an empty queue holds up the others as little
+// as possible while every SQS server is still asked for messages.
+const laneWait = time.Second
+
+type lane struct {
+	priority models.JobPriority
+	queue    interfaces.PollingQueue
+	weight   int
+	current  int
+}
+
+// PriorityQueue sends each message to the queue for its priority and
+// receives from them with weighted fairness: every receive polls the queues
+// in turn, starting from one picked by smooth weighted round-robin, and takes
+// the first messages it finds. Higher priorities are picked first more often,
+// while lower ones are still picked first in proportion to their weight, so
+// a steady stream of urgent jobs can't starve them.
+type PriorityQueue struct {
+	mu     sync.Mutex
+	lanes  []*lane
+	routes map[models.JobPriority]*lane
+}
+
+// NewPriorityQueue routes each priority to its queue in queues. A normal
+// priority queue is required; priorities without one share it.
+func NewPriorityQueue(queues map[models.JobPriority]interfaces.PollingQueue, weights map[models.JobPriority]int) (*PriorityQueue, error) {
+	if _, ok := queues[models.JobPriorityNormal]; !ok {
+		return nil, errors.New("a normal priority queue is required")
+	}
+
+	q := &PriorityQueue{routes: make(map[models.JobPriority]*lane)}
+	for _, priority := range models.JobPriorities {
+		pq, ok := queues[priority]
+		if !ok {
+			continue
+		}
+		weight := weights[priority]
+		if weight <= 0 {
+			weight = DefaultPriorityWeights[priority]
+		}
+		l := &lane{priority: priority, queue: pq, weight: weight}
+		q.lanes = append(q.lanes, l)
+		q.routes[priority] = l
+	}
+	for _, priority := range models.JobPriorities {
+		if _, ok := q.routes[priority]; !ok {
+			q.routes[priority] = q.routes[models.JobPriorityNormal]
+		}
+	}
+	return q, nil
+}
+
+// route returns the lane for priority, unset meaning normal
+func (q *PriorityQueue) route(priority models.JobPriority) *lane {
+	if l, ok := q.routes[priority]; ok {
+		return l
+	}
+	return q.routes[models.JobPriorityNormal]
+}
+
+func (q *PriorityQueue) SendMessage(ctx context.Context, jobID string, opts ...queue.SendOption) error {
+	options := queue.ApplySendOptions(opts...)
+	return q.route(options.Priority).queue.SendMessage(ctx, jobID, opts...)
+}
+
+// ReceiveMessages long-polls the queues one after another, in the order
+// picked for this receive, and returns the messages of the first that has
+// any. Each poll runs to its end, so no receive is abandoned after SQS has
+// handed it messages, which would hide them for the visibility timeout and
+// count towards dead-lettering them.
+func (q *PriorityQueue) ReceiveMessages(ctx context.Context, maxMessages int) ([]queue.Message, error) {
+	for _, l := range q.order() {
+		messages, err := l.queue.Poll(ctx, maxMessages, laneWait)
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive %s priority messages: %w", l.priority, err)
+		}
+		if len(messages) > 0 {
+			return l.tag(messages), nil
+		}
+	}
+	return nil, nil
+}
+
+// order returns the lanes with the one picked by smooth weighted round-robin
+// first, then the rest from highest priority to lowest
+func (q *PriorityQueue) order() []*lane {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	total := 0
+	var picked *lane
+	for _, l := range q.lanes {
+		l.current += l.weight
+		total += l.weight
+		if picked == nil || l.current > picked.current {
+			picked = l
+		}
+	}
+	picked.current -= total
+
+	order := make([]*lane, 0, len(q.lanes))
+	order = append(order, picked)
+	for _, l := range q.lanes {
+		if l != picked {
+			order = append(order, l)
+		}
+	}
+	return order
+}
+
+// tag marks messages with the lane's priority so they are acknowledged on
+// the queue they came from
+func (l *lane) tag(messages []queue.Message) []queue.Message {
+	for i := range messages {
+		messages[i].Priority = l.priority
+	}
+	return messages
+}
+
+func (q *PriorityQueue) Ack(ctx context.Context, msg queue.Message) error {
+	return q.route(msg.Priority).queue.Ack(ctx, msg)
+}
+
+func (q *PriorityQueue) Nack(ctx context.Context, msg queue.Message, delay time.Duration) error {
+	return q.route(msg.Priority).queue.Nack(ctx, msg, delay)
+}
+
+func (q *PriorityQueue) Extend(ctx context.Context, msg queue.Message, timeout time.Duration) error {
+	return q.route(msg.Priority).queue.Extend(ctx, msg, timeout)
+}
+
+// PeekDeadLetters returns up to maxMessages dead letters across the queues
+// that have a dead-letter queue
+func (q *PriorityQueue) PeekDeadLetters(ctx context.Context, maxMessages int) ([]queue.DeadLetter, error) {
+	var letters []queue.DeadLetter
+	found := false
+	for _, dlq := range q.deadLetterQueues() {
+		peeked, err := dlq.PeekDeadLetters(ctx, maxMessages-len(letters))
+		if errors.Is(err, queue.ErrNoDeadLetterQueue) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		letters = append(letters, peeked...)
+		if len(letters) >= maxMessages {
+			break
+		}
+	}
+	if !found {
+		return nil, queue.ErrNoDeadLetterQueue
+	}
+	return letters, nil
+}
+
+// RedriveDeadLetters moves the dead letters with messageIDs back to the
+// queues they were dead-lettered from
+func (q *PriorityQueue) RedriveDeadLetters(ctx context.Context, messageIDs []string) ([]queue.DeadLetter, error) {
+	var letters []queue.DeadLetter
+	found := false
+	for _, dlq := range q.deadLetterQueues() {
+		redriven, err := dlq.RedriveDeadLetters(ctx, messageIDs)
+		if errors.Is(err, queue.ErrNoDeadLetterQueue) {
+			continue
+		}
+		if err != nil {
+			return letters, err
+		}
+		found = true
+		letters = append(letters, redriven...)
+	}
+	if !found {
+		return nil, queue.ErrNoDeadLetterQueue
+	}
+	return letters, nil
+}
+
+// sharedDeadLetterQueue is a queue whose dead-letter queue other queues may
+// share, such as the SQS queues for each priority
+type sharedDeadLetterQueue interface {
+	DeadLetterQueueURL() string
+}
+
+// deadLetterQueues returns the lanes' dead-letter queues, once each when
+// lanes share one
+func (q *PriorityQueue) deadLetterQueues() []interfaces.DeadLetterQueue {
+	var dlqs []interfaces.DeadLetterQueue
+	seen := make(map[string]bool)
+	for _, l := range q.lanes {
+		dlq, ok := l.queue.(interfaces.DeadLetterQueue)
+		if !ok {
+			continue
+		}
+		if shared, ok := l.queue.(sharedDeadLetterQueue); ok {
+			url := shared.DeadLetterQueueURL()
+			if seen[url] {
+				continue
+			}
+			seen[url] = true
+		}
+		dlqs = append(dlqs, dlq)
+	}
+	return dlqs
+}
@@ -0,0 +1,228 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+)
+
+func newMemoryQueues(priorities ...models.JobPriority) map[models.JobPriority]*queue.MemoryQueue {
+	queues := make(map[models.JobPriority]*queue.MemoryQueue)
+	for _, priority := range priorities {
+		q := queue.NewMemoryQueue(time.Minute, 1)
+		q.SetWaitTime(0)
+		queues[priority] = q
+	}
+	return queues
+}
+
+func newPriorityQueue(t *testing.T, queues map[models.JobPriority]*queue.MemoryQueue) *PriorityQueue {
+	polling := make(map[models.JobPriority]interfaces.PollingQueue, len(queues))
+	for priority, q := range queues {
+		polling[priority] = q
+	}
+	pq, err := NewPriorityQueue(polling, nil)
+	require.NoError(t, err)
+	return pq
+}
+
+func TestNewPriorityQueue_RequiresNormal(t *testing.T) {
+	_, err := NewPriorityQueue(map[models.JobPriority]interfaces.PollingQueue{
+		models.JobPriorityHigh: queue.NewMemoryQueue(time.Minute, 0),
+	}, nil)
+	assert.Error(t, err)
+}
+
+func TestPriorityQueue_Routing(t *testing.T) {
+	ctx := context.Background()
+	queues := newMemoryQueues(models.JobPriorityHigh, models.JobPriorityNormal)
+	pq := newPriorityQueue(t, queues)
+
+	require.NoError(t, pq.SendMessage(ctx, "urgent", queue.WithPriority(models.JobPriorityHigh)))
+	require.NoError(t, pq.SendMessage(ctx, "default"))
+	// Low has no queue of its own and shares normal's
+	require.NoError(t, pq.SendMessage(ctx, "housekeeping", queue.WithPriority(models.JobPriorityLow)))
+	assert.Equal(t, 1, queues[models.JobPriorityHigh].Len())
+	assert.Equal(t, 2, queues[models.JobPriorityNormal].Len())
+
+	messages, err := pq.ReceiveMessages(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, models.JobPriorityHigh, messages[0].Priority)
+
+	// Acks go back to the queue the message came from
+	require.NoError(t, pq.Ack(ctx, messages[0]))
+	assert.Equal(t, 0, queues[models.JobPriorityHigh].Len())
+
+	messages, err = pq.ReceiveMessages(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, models.JobPriorityNormal, messages[0].Priority)
+	require.NoError(t, pq.Nack(ctx, messages[0], 0))
+	require.NoError(t, pq.Extend(ctx, messages[1], time.Minute))
+}
+
+func TestPriorityQueue_WeightedFairness(t *testing.T) {
+	ctx := context.Background()
+	queues := newMemoryQueues(models.JobPriorityHigh, models.JobPriorityNormal, models.JobPriorityLow)
+	pq := newPriorityQueue(t, queues)
+
+	for priority, q := range queues {
+		for i := 0; i < 20; i++ {
+			require.NoError(t, q.SendMessage(ctx, string(priority)))
+		}
+	}
+
+	// With every queue busy, each gets receives in proportion to its weight
+	received := make(map[models.JobPriority]int)
+	for i := 0; i < 10; i++ {
+		messages, err := pq.ReceiveMessages(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		received[messages[0].Priority]++
+		require.NoError(t, pq.Ack(ctx, messages[0]))
+	}
+	assert.Equal(t, map[models.JobPriority]int{
+		models.JobPriorityHigh:   6,
+		models.JobPriorityNormal: 3,
+		models.JobPriorityLow:    1,
+	}, received)
+
+	// A queue's turn passes to the others while it is empty
+	for queues[models.JobPriorityHigh].Len() > 0 {
+		messages, err := pq.ReceiveMessages(ctx, 10)
+		require.NoError(t, err)
+		for _, msg := range messages {
+			require.NoError(t, pq.Ack(ctx, msg))
+		}
+	}
+	messages, err := pq.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.NotEqual(t, models.JobPriorityHigh, messages[0].Priority)
+}
+
+func TestPriorityQueue_LongPollsEveryQueue(t *testing.T) {
+	ctx := context.Background()
+	queues := newMemoryQueues(models.JobPriorityHigh, models.JobPriorityNormal, models.JobPriorityLow)
+	pq := newPriorityQueue(t, queues)
+
+	// A message arriving on any queue while all are empty is picked up
+	// within one round of polls
+	for _, priority := range []models.JobPriority{models.JobPriorityLow, models.JobPriorityNormal, models.JobPriorityHigh} {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = queues[priority].SendMessage(ctx, string(priority))
+		}()
+
+		var messages []queue.Message
+		start := time.Now()
+		for len(messages) == 0 && time.Since(start) < 2*3*laneWait {
+			var err error
+			messages, err = pq.ReceiveMessages(ctx, 10)
+			require.NoError(t, err)
+		}
+		require.Len(t, messages, 1)
+		assert.Equal(t, priority, messages[0].Priority)
+		assert.Less(t, time.Since(start), 3*laneWait+laneWait/2)
+		require.NoError(t, pq.Ack(ctx, messages[0]))
+	}
+}
+
+func TestPriorityQueue_NoReceiveIsWasted(t *testing.T) {
+	ctx := context.Background()
+	// Every queue dead-letters a message on its second receive, so a
+	// receive that took messages without returning them would lose them
+	queues := newMemoryQueues(models.JobPriorityHigh, models.JobPriorityNormal, models.JobPriorityLow)
+	pq := newPriorityQueue(t, queues)
+
+	// Messages land on every queue at once while a receive is waiting
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		for priority, q := range queues {
+			for i := 0; i < 3; i++ {
+				_ = q.SendMessage(ctx, string(priority))
+			}
+		}
+	}()
+
+	received := make(map[models.JobPriority]int)
+	deadline := time.Now().Add(10 * laneWait)
+	for total := 0; total < 9 && time.Now().Before(deadline); {
+		messages, err := pq.ReceiveMessages(ctx, 2)
+		require.NoError(t, err)
+		for _, msg := range messages {
+			received[msg.Priority]++
+			total++
+			require.NoError(t, pq.Ack(ctx, msg))
+		}
+	}
+	assert.Equal(t, map[models.JobPriority]int{
+		models.JobPriorityHigh:   3,
+		models.JobPriorityNormal: 3,
+		models.JobPriorityLow:    3,
+	}, received)
+
+	letters, err := pq.PeekDeadLetters(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+// sharedDLQ is a memory queue reporting a dead-letter queue it shares with
+// other queues
+type sharedDLQ struct {
+	*queue.MemoryQueue
+	url string
+}
+
+func (q sharedDLQ) DeadLetterQueueURL() string { return q.url }
+
+func TestPriorityQueue_SharedDeadLetterQueue(t *testing.T) {
+	queues := newMemoryQueues(models.JobPriorityHigh, models.JobPriorityNormal)
+	pq, err := NewPriorityQueue(map[models.JobPriority]interfaces.PollingQueue{
+		models.JobPriorityHigh:   sharedDLQ{queues[models.JobPriorityHigh], "dlq"},
+		models.JobPriorityNormal: sharedDLQ{queues[models.JobPriorityNormal], "dlq"},
+	}, nil)
+	require.NoError(t, err)
+
+	// Queues sharing a dead-letter queue are only asked for it once
+	assert.Len(t, pq.deadLetterQueues(), 1)
+}
+
+func TestPriorityQueue_DeadLetters(t *testing.T) {
+	ctx := context.Background()
+	queues := newMemoryQueues(models.JobPriorityHigh, models.JobPriorityNormal)
+	pq := newPriorityQueue(t, queues)
+
+	// Each queue dead-letters after one receive
+	for _, priority := range []models.JobPriority{models.JobPriorityHigh, models.JobPriorityNormal} {
+		require.NoError(t, pq.SendMessage(ctx, string(priority), queue.WithPriority(priority)))
+		messages, err := pq.ReceiveMessages(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		require.NoError(t, pq.Nack(ctx, messages[0], 0))
+	}
+	// Receives move them to the dead-letter queues
+	for _, q := range queues {
+		_, err := q.Poll(ctx, 10, 0)
+		require.NoError(t, err)
+	}
+
+	letters, err := pq.PeekDeadLetters(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+
+	ids := []string{letters[0].MessageID, letters[1].MessageID}
+	redriven, err := pq.RedriveDeadLetters(ctx, ids)
+	require.NoError(t, err)
+	assert.Len(t, redriven, 2)
+	assert.Equal(t, 1, queues[models.JobPriorityHigh].Len())
+	assert.Equal(t, 1, queues[models.JobPriorityNormal].Len())
+}
@@ -0,0 +1,169 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+var ErrNoDeadLetterQueue = errors.New("dead-letter queue not configured")
+
+const (
+	// redriveVisibility hides DLQ messages while a redrive decides on them
+	redriveVisibility = 30
+
+	// maxDLQRounds bounds how many receive calls a peek or redrive makes
+	maxDLQRounds = 20
+)
+
+// DeadLetter is a message that exhausted its receives on the main queue
+type DeadLetter struct {
+	MessageID    string    `json:"message_id"`
+	JobID        string    `json:"job_id,omitempty"`
+	Body         string    `json:"body"`
+	ReceiveCount int       `json:"receive_count"`
+	SentAt       time.Time `json:"sent_at"`
+}
+
+// PeekDeadLetters returns up to maxMessages DLQ messages without removing
+// them. SQS has no true peek, so messages are received with a zero
+// visibility timeout; the result is a best-effort sample of the queue.
+func (s *SQSClient) PeekDeadLetters(ctx context.Context, maxMessages int) ([]DeadLetter, error) {
+	if s.dlqURL == "" {
+		return nil, ErrNoDeadLetterQueue
+	}
+
+	seen := make(map[string]bool)
+	var letters []DeadLetter
+	for round := 0; round < maxDLQRounds && len(letters) < maxMessages; round++ {
+		messages, err := s.receiveDeadLetters(ctx, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		added := 0
+		for _, msg := range messages {
+			letter := toDeadLetter(fromSQS(msg))
+			if seen[letter.MessageID] || len(letters) >= maxMessages {
+				continue
+			}
+			seen[letter.MessageID] = true
+			letters = append(letters, letter)
+			added++
+		}
+		if added == 0 {
+			break
+		}
+	}
+
+	return letters, nil
+}
+
+// RedriveDeadLetters moves the given DLQ messages back to the queue each was
+// sent to, or to the main queue for messages that don't say, and returns the
+// messages that were moved. Messages not selected are released immediately.
+// IDs not found within the scan are skipped.
+func (s *SQSClient) RedriveDeadLetters(ctx context.Context, messageIDs []string) ([]DeadLetter, error) {
+	if s.dlqURL == "" {
+		return nil, ErrNoDeadLetterQueue
+	}
+
+	wanted := make(map[string]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		wanted[id] = true
+	}
+
+	var moved []DeadLetter
+	for round := 0; round < maxDLQRounds && len(moved) < len(wanted); round++ {
+		messages, err := s.receiveDeadLetters(ctx, redriveVisibility)
+		if err != nil {
+			return moved, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		for _, msg := range messages {
+			id := aws.ToString(msg.MessageId)
+			if !wanted[id] {
+				s.releaseDeadLetter(ctx, msg)
+				continue
+			}
+
+			_, err := s.client.SendMessage(ctx, &sqs.SendMessageInput{
+				QueueUrl:          aws.String(s.redriveTarget(msg)),
+				MessageBody:       msg.Body,
+				MessageAttributes: msg.MessageAttributes,
+			})
+			if err != nil {
+				s.releaseDeadLetter(ctx, msg)
+				return moved, fmt.Errorf("failed to redrive message %s: %w", id, err)
+			}
+			if _, err := s.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      &s.dlqURL,
+				ReceiptHandle: msg.ReceiptHandle,
+			}); err != nil {
+				return moved, fmt.Errorf("redrove message %s but failed to delete it from the DLQ: %w", id, err)
+			}
+
+			delete(wanted, id)
+			moved = append(moved, toDeadLetter(fromSQS(msg)))
+		}
+	}
+
+	return moved, nil
+}
+
+// redriveTarget returns the URL of the queue msg was dead-lettered from
+func (s *SQSClient) redriveTarget(msg types.Message) string {
+	if source := aws.ToString(msg.MessageAttributes[sourceQueueAttribute].StringValue); source != "" {
+		return source
+	}
+	return s.queueURL
+}
+
+func (s *SQSClient) receiveDeadLetters(ctx context.Context, visibility int32) ([]types.Message, error) {
+	result, err := s.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            &s.dlqURL,
+		MaxNumberOfMessages: maxReceiveBatch,
+		VisibilityTimeout:   visibility,
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+			types.MessageSystemAttributeNameSentTimestamp,
+		},
+		MessageAttributeNames: []string{"All"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive from DLQ: %w", err)
+	}
+	return result.Messages, nil
+}
+
+func (s *SQSClient) releaseDeadLetter(ctx context.Context, msg types.Message) {
+	_, _ = s.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &s.dlqURL,
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: 0,
+	})
+}
+
+func toDeadLetter(msg Message) DeadLetter {
+	letter := DeadLetter{
+		MessageID:    msg.ID,
+		Body:         msg.Body,
+		ReceiveCount: msg.ReceiveCount,
+		SentAt:       msg.EnqueuedAt,
+	}
+	if jobMsg, err := msg.JobMessage(); err == nil {
+		letter.JobID = jobMsg.JobID
+	}
+	return letter
+}
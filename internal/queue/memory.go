@@ -0,0 +1,299 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+)
+
+type memoryMessage struct {
+	id            string
+	body          string
+	seq           uint64
+	receiptHandle string
+	receiveCount  int
+	sentAt        time.Time
+	visibleAt     time.Time
+	dead          bool
+	attributes    map[string]string
+}
+
+// MemoryQueue is an in-process queue with SQS semantics: received messages
+// are hidden for the visibility timeout and redelivered unless deleted,
+// delayed messages stay hidden until due, and messages received more than
+// maxReceiveCount times are dead-lettered. Visible messages are delivered
+// oldest first.
+type MemoryQueue struct {
+	mu                sync.Mutex
+	messages          map[string]*memoryMessage
+	seq               uint64
+	visibilityTimeout time.Duration
+	maxReceiveCount   int
+	waitTime          time.Duration
+	notify            chan struct{}
+}
+
+// NewMemoryQueue creates a queue. A maxReceiveCount of zero disables
+// dead-lettering.
+func NewMemoryQueue(visibilityTimeout time.Duration, maxReceiveCount int) *MemoryQueue {
+	return &MemoryQueue{
+		messages:          make(map[string]*memoryMessage),
+		visibilityTimeout: visibilityTimeout,
+		maxReceiveCount:   maxReceiveCount,
+		waitTime:          pgWaitTime,
+		notify:            make(chan struct{}),
+	}
+}
+
+// SetWaitTime changes how long ReceiveMessages waits for a message
+func (q *MemoryQueue) SetWaitTime(wait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.waitTime = wait
+}
+
+func (q *MemoryQueue) SendMessage(ctx context.Context, jobID string, opts ...SendOption) error {
+	options := ApplySendOptions(opts...)
+
+	body, err := json.Marshal(JobMessage{JobID: jobID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.seq++
+	msg := &memoryMessage{
+		id:         uuid.New().String(),
+		body:       string(body),
+		seq:        q.seq,
+		sentAt:     now,
+		visibleAt:  now.Add(options.Delay),
+		attributes: tracing.Inject(ctx),
+	}
+	q.messages[msg.id] = msg
+	q.broadcast()
+	return nil
+}
+
+func (q *MemoryQueue) ReceiveMessages(ctx context.Context, maxMessages int) ([]Message, error) {
+	q.mu.Lock()
+	wait := q.waitTime
+	q.mu.Unlock()
+	return q.Poll(ctx, maxMessages, wait)
+}
+
+// Poll receives up to maxMessages messages, waiting at most wait for one to
+// become visible
+func (q *MemoryQueue) Poll(ctx context.Context, maxMessages int, wait time.Duration) ([]Message, error) {
+	if maxMessages < 1 {
+		maxMessages = 1
+	}
+	if maxMessages > maxReceiveBatch {
+		maxMessages = maxReceiveBatch
+	}
+
+	deadline := time.Now().Add(wait)
+
+	for {
+		q.mu.Lock()
+		messages, nextVisible := q.receiveLocked(maxMessages)
+		notify := q.notify
+		q.mu.Unlock()
+
+		if len(messages) > 0 {
+			return messages, nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, nil
+		}
+		if !nextVisible.IsZero() && time.Until(nextVisible) < wait {
+			wait = time.Until(nextVisible)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-notify:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// receiveLocked returns visible messages and, if there are none, when the
+// next hidden message becomes visible
+func (q *MemoryQueue) receiveLocked(maxMessages int) ([]Message, time.Time) {
+	now := time.Now()
+
+	var visible []*memoryMessage
+	var nextVisible time.Time
+	for _, msg := range q.messages {
+		if msg.dead {
+			continue
+		}
+		if msg.visibleAt.After(now) {
+			if nextVisible.IsZero() || msg.visibleAt.Before(nextVisible) {
+				nextVisible = msg.visibleAt
+			}
+			continue
+		}
+		if q.maxReceiveCount > 0 && msg.receiveCount >= q.maxReceiveCount {
+			msg.dead = true
+			msg.receiptHandle = ""
+			continue
+		}
+		visible = append(visible, msg)
+	}
+
+	sort.Slice(visible, func(i, j int) bool {
+		return visible[i].seq < visible[j].seq
+	})
+	if len(visible) > maxMessages {
+		visible = visible[:maxMessages]
+	}
+
+	messages := make([]Message, 0, len(visible))
+	for _, msg := range visible {
+		msg.receiveCount++
+		msg.receiptHandle = uuid.New().String()
+		msg.visibleAt = now.Add(q.visibilityTimeout)
+		messages = append(messages, msg.toMessage())
+	}
+	return messages, nextVisible
+}
+
+// Ack removes a message by its latest receipt handle. Handles from earlier
+// receives are rejected, like a receipt from an expired lease.
+func (q *MemoryQueue) Ack(ctx context.Context, msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m, ok := q.received(msg)
+	if !ok {
+		return ErrInvalidReceiptHandle
+	}
+	delete(q.messages, m.id)
+	return nil
+}
+
+func (q *MemoryQueue) Nack(ctx context.Context, msg Message, delay time.Duration) error {
+	return q.setVisibleAt(msg, time.Now().Add(delay))
+}
+
+func (q *MemoryQueue) Extend(ctx context.Context, msg Message, timeout time.Duration) error {
+	return q.setVisibleAt(msg, time.Now().Add(timeout))
+}
+
+func (q *MemoryQueue) setVisibleAt(msg Message, visibleAt time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m, ok := q.received(msg)
+	if !ok {
+		return ErrInvalidReceiptHandle
+	}
+	m.visibleAt = visibleAt
+	q.broadcast()
+	return nil
+}
+
+// received finds the message msg was received as, if its receipt handle is
+// still current; callers hold q.mu
+func (q *MemoryQueue) received(msg Message) (*memoryMessage, bool) {
+	m, ok := q.messages[msg.ID]
+	if !ok || m.dead || m.receiptHandle == "" || m.receiptHandle != msg.ReceiptHandle {
+		return nil, false
+	}
+	return m, true
+}
+
+func (q *MemoryQueue) PeekDeadLetters(ctx context.Context, maxMessages int) ([]DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var dead []*memoryMessage
+	for _, msg := range q.messages {
+		if msg.dead {
+			dead = append(dead, msg)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool {
+		return dead[i].seq < dead[j].seq
+	})
+	if len(dead) > maxMessages {
+		dead = dead[:maxMessages]
+	}
+
+	letters := make([]DeadLetter, 0, len(dead))
+	for _, msg := range dead {
+		letters = append(letters, toDeadLetter(msg.toMessage()))
+	}
+	return letters, nil
+}
+
+func (q *MemoryQueue) RedriveDeadLetters(ctx context.Context, messageIDs []string) ([]DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var moved []DeadLetter
+	now := time.Now()
+	for _, id := range messageIDs {
+		msg, ok := q.messages[id]
+		if !ok || !msg.dead {
+			continue
+		}
+		msg.dead = false
+		msg.receiveCount = 0
+		msg.visibleAt = now
+		moved = append(moved, toDeadLetter(msg.toMessage()))
+	}
+	if len(moved) > 0 {
+		q.broadcast()
+	}
+	return moved, nil
+}
+
+// Len returns the number of live (not dead-lettered) messages, visible or not
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := 0
+	for _, msg := range q.messages {
+		if !msg.dead {
+			n++
+		}
+	}
+	return n
+}
+
+// broadcast wakes every waiting receiver; callers hold q.mu
+func (q *MemoryQueue) broadcast() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+func (m *memoryMessage) toMessage() Message {
+	return Message{
+		ID:            m.id,
+		Body:          m.body,
+		ReceiptHandle: m.receiptHandle,
+		ReceiveCount:  m.receiveCount,
+		EnqueuedAt:    m.sentAt,
+		Attributes:    m.attributes,
+	}
+}
@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jobIDs(t *testing.T, messages []Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		body, err := msg.JobMessage()
+		require.NoError(t, err)
+		ids = append(ids, body.JobID)
+	}
+	return ids
+}
+
+func TestMemoryQueue_OrderingAndDelete(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, q.SendMessage(ctx, id))
+	}
+
+	messages, err := q.ReceiveMessages(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, jobIDs(t, messages))
+
+	// Received messages are hidden from other consumers
+	messages, err = q.ReceiveMessages(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, jobIDs(t, messages))
+
+	require.NoError(t, q.Ack(ctx, messages[0]))
+	assert.ErrorIs(t, q.Ack(ctx, messages[0]), ErrInvalidReceiptHandle)
+	assert.Equal(t, 2, q.Len())
+}
+
+func TestMemoryQueue_VisibilityTimeoutRedelivers(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(30*time.Millisecond, 0)
+	q.SetWaitTime(time.Second)
+
+	require.NoError(t, q.SendMessage(ctx, "job"))
+
+	first, err := q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+
+	// Not deleted, so it comes back once the timeout expires
+	second, err := q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, first[0].ID, second[0].ID)
+	assert.Equal(t, 2, second[0].ReceiveCount)
+
+	// The stale receipt handle no longer acks or extends the message
+	assert.ErrorIs(t, q.Ack(ctx, first[0]), ErrInvalidReceiptHandle)
+	assert.ErrorIs(t, q.Extend(ctx, first[0], time.Minute), ErrInvalidReceiptHandle)
+	assert.NoError(t, q.Ack(ctx, second[0]))
+}
+
+func TestMemoryQueue_NackAndExtend(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(30*time.Millisecond, 0)
+	q.SetWaitTime(0)
+
+	require.NoError(t, q.SendMessage(ctx, "job"))
+	messages, err := q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	// Extending keeps the message hidden past its original timeout
+	require.NoError(t, q.Extend(ctx, messages[0], time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	hidden, err := q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, hidden)
+
+	// Nacking makes it visible again straight away
+	require.NoError(t, q.Nack(ctx, messages[0], 0))
+	again, err := q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, messages[0].ID, again[0].ID)
+	assert.Equal(t, 2, again[0].ReceiveCount)
+}
+
+func TestMemoryQueue_DelayAndLongPoll(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(time.Second)
+
+	require.NoError(t, q.SendMessage(ctx, "later", WithDelay(50*time.Millisecond)))
+
+	start := time.Now()
+	messages, err := q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"later"}, jobIDs(t, messages))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// A waiting receiver wakes up on send
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.SendMessage(ctx, "now")
+	}()
+	messages, err = q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"now"}, jobIDs(t, messages))
+
+	// Cancelling the context ends the wait
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = q.ReceiveMessages(cancelled, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMemoryQueue_DeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(time.Millisecond, 2)
+	q.SetWaitTime(0)
+
+	require.NoError(t, q.SendMessage(ctx, "poison"))
+	for i := 0; i < 2; i++ {
+		messages, err := q.ReceiveMessages(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	messages, err := q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+	assert.Equal(t, 0, q.Len())
+
+	letters, err := q.PeekDeadLetters(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, "poison", letters[0].JobID)
+	assert.Equal(t, 2, letters[0].ReceiveCount)
+
+	moved, err := q.RedriveDeadLetters(ctx, []string{letters[0].MessageID, "missing"})
+	require.NoError(t, err)
+	assert.Len(t, moved, 1)
+
+	messages, err = q.ReceiveMessages(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"poison"}, jobIDs(t, messages))
+}
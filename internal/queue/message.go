@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+// Message is a message received from any queue backend
+type Message struct {
+	// ID identifies the message across receives
+	ID   string
+	Body string
+	// ReceiptHandle identifies this receive of the message; Ack, Nack and
+	// Extend need it, and it stops working once the message is redelivered
+	ReceiptHandle string
+	// Attributes holds string metadata sent with the message
+	Attributes   map[string]string
+	ReceiveCount int
+	EnqueuedAt   time.Time
+	// Priority is the priority of the queue the message came from, when the
+	// backend has one queue per priority
+	Priority models.JobPriority
+}
+
+// JobMessage decodes the message body
+func (m Message) JobMessage() (JobMessage, error) {
+	var jobMsg JobMessage
+	if err := json.Unmarshal([]byte(m.Body), &jobMsg); err != nil {
+		return JobMessage{}, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	return jobMsg, nil
+}
@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+// MaxDelay is the longest delivery delay SQS supports for a single message
+const MaxDelay = 15 * time.Minute
+
+// SendOption customises a single SendMessage call
+type SendOption func(*SendOptions)
+
+// SendOptions holds the resolved options for a SendMessage call
+type SendOptions struct {
+	Delay    time.Duration
+	Priority models.JobPriority
+}
+
+// WithDelay postpones delivery of the message; delays above MaxDelay are capped
+func WithDelay(delay time.Duration) SendOption {
+	return func(o *SendOptions) {
+		o.Delay = delay
+	}
+}
+
+// WithPriority sends the message to the queue for priority, where the backend
+// has one per priority
+func WithPriority(priority models.JobPriority) SendOption {
+	return func(o *SendOptions) {
+		o.Priority = priority
+	}
+}
+
+// ApplySendOptions resolves opts into a SendOptions value
+func ApplySendOptions(opts ...SendOption) SendOptions {
+	var o SendOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Delay < 0 {
+		o.Delay = 0
+	}
+	if o.Delay > MaxDelay {
+		o.Delay = MaxDelay
+	}
+	return o
+}
@@ -0,0 +1,240 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
+)
+
+const (
+	// pgWaitTime matches the SQS long-poll wait used by SQSClient
+	pgWaitTime     = 20 * time.Second
+	pgPollInterval = 500 * time.Millisecond
+)
+
+var ErrInvalidReceiptHandle = errors.New("invalid receipt handle")
+
+// PostgresQueue implements the queue on a Postgres table. Receives lock rows
+// with FOR UPDATE SKIP LOCKED and hide them for the visibility timeout, and
+// messages received more than maxReceiveCount times are dead-lettered, the
+// same as the SQS redrive policy.
+type PostgresQueue struct {
+	db                *gorm.DB
+	name              string
+	visibilityTimeout time.Duration
+	maxReceiveCount   int
+	waitTime          time.Duration
+}
+
+func NewPostgresQueue(db *gorm.DB, cfg *config.Config) *PostgresQueue {
+	return &PostgresQueue{
+		db:                db,
+		name:              cfg.QueueName,
+		visibilityTimeout: cfg.QueueVisibilityTimeout,
+		maxReceiveCount:   cfg.QueueMaxReceiveCount,
+		waitTime:          pgWaitTime,
+	}
+}
+
+// Named returns a queue with the same settings on the messages of another
+// queue name
+func (q *PostgresQueue) Named(name string) *PostgresQueue {
+	named := *q
+	named.name = name
+	return &named
+}
+
+func (q *PostgresQueue) SendMessage(ctx context.Context, jobID string, opts ...SendOption) error {
+	options := ApplySendOptions(opts...)
+
+	body, err := json.Marshal(JobMessage{JobID: jobID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return q.db.WithContext(ctx).Create(&models.QueueMessage{
+		Queue:     q.name,
+		Body:      string(body),
+		VisibleAt: time.Now().Add(options.Delay),
+		// Carries the trace context on to the worker, as on SQS
+		Attributes: tracing.Inject(ctx),
+	}).Error
+}
+
+// ReceiveMessages waits up to the long-poll wait time for visible messages
+func (q *PostgresQueue) ReceiveMessages(ctx context.Context, maxMessages int) ([]Message, error) {
+	return q.Poll(ctx, maxMessages, q.waitTime)
+}
+
+// Poll receives up to maxMessages messages, waiting at most wait for one to
+// become visible
+func (q *PostgresQueue) Poll(ctx context.Context, maxMessages int, wait time.Duration) ([]Message, error) {
+	if maxMessages < 1 {
+		maxMessages = 1
+	}
+	if maxMessages > maxReceiveBatch {
+		maxMessages = maxReceiveBatch
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		messages, err := q.receive(ctx, maxMessages)
+		if err != nil || len(messages) > 0 || time.Now().After(deadline) {
+			return messages, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pgPollInterval):
+		}
+	}
+}
+
+func (q *PostgresQueue) receive(ctx context.Context, maxMessages int) ([]Message, error) {
+	db := q.db.WithContext(ctx)
+	now := time.Now()
+
+	// Messages whose last receive expired with no receives left go to the DLQ
+	if q.maxReceiveCount > 0 {
+		err := db.Model(&models.QueueMessage{}).
+			Where("queue = ? AND dead_at IS NULL AND visible_at <= ? AND receive_count >= ?", q.name, now, q.maxReceiveCount).
+			Updates(map[string]interface{}{"dead_at": now, "receipt_handle": nil}).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to dead-letter messages: %w", err)
+		}
+	}
+
+	var rows []models.QueueMessage
+	err := db.Raw(`
+		UPDATE queue_messages
+		SET receipt_handle = gen_random_uuid(), receive_count = receive_count + 1, visible_at = ?
+		WHERE id IN (
+			SELECT id FROM queue_messages
+			WHERE queue = ? AND dead_at IS NULL AND visible_at <= ?
+			ORDER BY visible_at, created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(q.visibilityTimeout), q.name, now, maxMessages,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive messages: %w", err)
+	}
+
+	messages := make([]Message, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, toMessage(row))
+	}
+	return messages, nil
+}
+
+func (q *PostgresQueue) Ack(ctx context.Context, msg Message) error {
+	handle, err := uuid.Parse(msg.ReceiptHandle)
+	if err != nil {
+		return ErrInvalidReceiptHandle
+	}
+	return q.db.WithContext(ctx).
+		Where("receipt_handle = ?", handle).
+		Delete(&models.QueueMessage{}).Error
+}
+
+func (q *PostgresQueue) Nack(ctx context.Context, msg Message, delay time.Duration) error {
+	return q.setVisibleAt(ctx, msg, time.Now().Add(delay))
+}
+
+func (q *PostgresQueue) Extend(ctx context.Context, msg Message, timeout time.Duration) error {
+	return q.setVisibleAt(ctx, msg, time.Now().Add(timeout))
+}
+
+// setVisibleAt moves a received message's visibility, failing with
+// ErrInvalidReceiptHandle once the message has been received again
+func (q *PostgresQueue) setVisibleAt(ctx context.Context, msg Message, visibleAt time.Time) error {
+	handle, err := uuid.Parse(msg.ReceiptHandle)
+	if err != nil {
+		return ErrInvalidReceiptHandle
+	}
+
+	result := q.db.WithContext(ctx).Model(&models.QueueMessage{}).
+		Where("receipt_handle = ? AND dead_at IS NULL", handle).
+		Update("visible_at", visibleAt)
+	if result.Error != nil {
+		return fmt.Errorf("failed to change message visibility: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidReceiptHandle
+	}
+	return nil
+}
+
+func (q *PostgresQueue) PeekDeadLetters(ctx context.Context, maxMessages int) ([]DeadLetter, error) {
+	var rows []models.QueueMessage
+	err := q.db.WithContext(ctx).
+		Where("queue = ? AND dead_at IS NOT NULL", q.name).
+		Order("dead_at").
+		Limit(maxMessages).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(rows))
+	for _, row := range rows {
+		letters = append(letters, toDeadLetter(toMessage(row)))
+	}
+	return letters, nil
+}
+
+func (q *PostgresQueue) RedriveDeadLetters(ctx context.Context, messageIDs []string) ([]DeadLetter, error) {
+	ids := make([]uuid.UUID, 0, len(messageIDs))
+	for _, id := range messageIDs {
+		if parsed, err := uuid.Parse(id); err == nil {
+			ids = append(ids, parsed)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var rows []models.QueueMessage
+	err := q.db.WithContext(ctx).Raw(`
+		UPDATE queue_messages
+		SET dead_at = NULL, receipt_handle = NULL, receive_count = 0, visible_at = ?
+		WHERE queue = ? AND dead_at IS NOT NULL AND id IN ?
+		RETURNING *`,
+		time.Now(), q.name, ids,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to redrive messages: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(rows))
+	for _, row := range rows {
+		letters = append(letters, toDeadLetter(toMessage(row)))
+	}
+	return letters, nil
+}
+
+func toMessage(row models.QueueMessage) Message {
+	msg := Message{
+		ID:           row.ID.String(),
+		Body:         row.Body,
+		ReceiveCount: row.ReceiveCount,
+		EnqueuedAt:   row.CreatedAt,
+		Attributes:   row.Attributes,
+	}
+	if row.ReceiptHandle != nil {
+		msg.ReceiptHandle = row.ReceiptHandle.String()
+	}
+	return msg
+}
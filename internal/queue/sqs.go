@@ -4,17 +4,39 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
 )
 
+const (
+	// maxReceiveBatch is the SQS limit on messages per ReceiveMessage call
+	maxReceiveBatch = 10
+
+	// maxVisibilityTimeout is the longest visibility timeout SQS accepts
+	maxVisibilityTimeout = 12 * time.Hour
+
+	// maxWaitTime is the longest long poll SQS accepts
+	maxWaitTime = 20 * time.Second
+
+	// sourceQueueAttribute names the message attribute holding the URL of
+	// the queue a message was sent to, which it keeps in the dead-letter
+	// queue, so a redrive returns it to that queue
+	sourceQueueAttribute = "source_queue"
+)
+
 type SQSClient struct {
 	client   *sqs.Client
 	queueURL string
+	dlqURL   string
 }
 
 type JobMessage struct {
@@ -29,6 +51,9 @@ func NewSQSClient(cfg *config.Config) (*SQSClient, error) {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
+	// Every SQS call gets a client span
+	otelaws.AppendMiddlewares(&awsCfg.APIOptions)
+
 	var sqsClient *sqs.Client
 	if cfg.SQSEndpoint != "" {
 		sqsClient = sqs.NewFromConfig(awsCfg, func(o *sqs.Options) {
@@ -41,10 +66,25 @@ func NewSQSClient(cfg *config.Config) (*SQSClient, error) {
 	return &SQSClient{
 		client:   sqsClient,
 		queueURL: cfg.SQSQueueURL,
+		dlqURL:   cfg.SQSDLQURL,
 	}, nil
 }
 
-func (s *SQSClient) SendMessage(ctx context.Context, jobID string) error {
+// ForQueue returns a client for another queue on the same connection, sharing
+// the original client's dead-letter queue
+func (s *SQSClient) ForQueue(queueURL string) *SQSClient {
+	return &SQSClient{client: s.client, queueURL: queueURL, dlqURL: s.dlqURL}
+}
+
+// DeadLetterQueueURL returns the URL of the dead-letter queue, empty if there
+// is none
+func (s *SQSClient) DeadLetterQueueURL() string {
+	return s.dlqURL
+}
+
+func (s *SQSClient) SendMessage(ctx context.Context, jobID string, opts ...SendOption) error {
+	options := ApplySendOptions(opts...)
+
 	message := JobMessage{JobID: jobID}
 	body, err := json.Marshal(message)
 	if err != nil {
@@ -52,35 +92,153 @@ func (s *SQSClient) SendMessage(ctx context.Context, jobID string) error {
 	}
 
 	_, err = s.client.SendMessage(ctx, &sqs.SendMessageInput{
-		QueueUrl:    &s.queueURL,
-		MessageBody: aws.String(string(body)),
+		QueueUrl:          &s.queueURL,
+		MessageBody:       aws.String(string(body)),
+		DelaySeconds:      int32(options.Delay / time.Second),
+		MessageAttributes: s.messageAttributes(ctx),
 	})
 	return err
 }
 
-func (s *SQSClient) ReceiveMessages(ctx context.Context) ([]types.Message, error) {
+// messageAttributes returns the attributes sent with a message: the trace
+// context of ctx and the queue it is sent to
+func (s *SQSClient) messageAttributes(ctx context.Context) map[string]types.MessageAttributeValue {
+	attributes := traceAttributes(ctx)
+	if attributes == nil {
+		attributes = make(map[string]types.MessageAttributeValue, 1)
+	}
+	attributes[sourceQueueAttribute] = types.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(s.queueURL),
+	}
+	return attributes
+}
+
+// traceAttributes returns the trace context of ctx as message attributes,
+// for the worker to continue the trace from
+func traceAttributes(ctx context.Context) map[string]types.MessageAttributeValue {
+	carrier := tracing.Inject(ctx)
+	if carrier == nil {
+		return nil
+	}
+	attributes := make(map[string]types.MessageAttributeValue, len(carrier))
+	for name, value := range carrier {
+		attributes[name] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	return attributes
+}
+
+// ReceiveMessages long-polls for up to maxMessages messages (1-10).
+func (s *SQSClient) ReceiveMessages(ctx context.Context, maxMessages int) ([]Message, error) {
+	return s.Poll(ctx, maxMessages, maxWaitTime)
+}
+
+// Poll receives up to maxMessages messages (1-10), waiting at most wait, in
+// whole seconds up to 20, for one to arrive. A zero wait is a short poll,
+// which only samples some of the SQS servers and can miss messages.
+func (s *SQSClient) Poll(ctx context.Context, maxMessages int, wait time.Duration) ([]Message, error) {
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxWaitTime {
+		wait = maxWaitTime
+	}
+	if maxMessages < 1 {
+		maxMessages = 1
+	}
+	if maxMessages > maxReceiveBatch {
+		maxMessages = maxReceiveBatch
+	}
+
 	result, err := s.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
 		QueueUrl:            &s.queueURL,
-		MaxNumberOfMessages: 10,
-		WaitTimeSeconds:     20,
+		MaxNumberOfMessages: int32(maxMessages),
+		WaitTimeSeconds:     int32(wait / time.Second),
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+			types.MessageSystemAttributeNameSentTimestamp,
+		},
+		MessageAttributeNames: []string{"All"},
 	})
 	if err != nil {
 		return nil, err
 	}
-	return result.Messages, nil
+
+	messages := make([]Message, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		messages = append(messages, fromSQS(msg))
+	}
+	return messages, nil
 }
 
-func (s *SQSClient) DeleteMessage(ctx context.Context, receiptHandle string) error {
+func (s *SQSClient) Ack(ctx context.Context, msg Message) error {
 	_, err := s.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
 		QueueUrl:      &s.queueURL,
-		ReceiptHandle: &receiptHandle,
+		ReceiptHandle: aws.String(msg.ReceiptHandle),
 	})
 	return err
 }
 
+func (s *SQSClient) Nack(ctx context.Context, msg Message, delay time.Duration) error {
+	return s.changeVisibility(ctx, msg, delay)
+}
+
+func (s *SQSClient) Extend(ctx context.Context, msg Message, timeout time.Duration) error {
+	return s.changeVisibility(ctx, msg, timeout)
+}
+
+func (s *SQSClient) changeVisibility(ctx context.Context, msg Message, timeout time.Duration) error {
+	if timeout < 0 {
+		timeout = 0
+	}
+	if timeout > maxVisibilityTimeout {
+		timeout = maxVisibilityTimeout
+	}
+	_, err := s.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &s.queueURL,
+		ReceiptHandle:     aws.String(msg.ReceiptHandle),
+		VisibilityTimeout: int32(timeout / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change message visibility: %w", err)
+	}
+	return nil
+}
+
 func (s *SQSClient) CreateQueueIfNotExists(ctx context.Context, queueName string) error {
 	_, err := s.client.CreateQueue(ctx, &sqs.CreateQueueInput{
 		QueueName: &queueName,
 	})
 	return err
-}
\ No newline at end of file
+}
+
+// fromSQS converts an SQS message, reading the receive count and sent time
+// from its system attributes
+func fromSQS(msg types.Message) Message {
+	m := Message{
+		ID:            aws.ToString(msg.MessageId),
+		Body:          aws.ToString(msg.Body),
+		ReceiptHandle: aws.ToString(msg.ReceiptHandle),
+	}
+
+	if n, err := strconv.Atoi(msg.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]); err == nil {
+		m.ReceiveCount = n
+	}
+	if ms, err := strconv.ParseInt(msg.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64); err == nil {
+		m.EnqueuedAt = time.UnixMilli(ms).UTC()
+	}
+
+	for name, value := range msg.MessageAttributes {
+		if value.StringValue == nil {
+			continue
+		}
+		if m.Attributes == nil {
+			m.Attributes = make(map[string]string, len(msg.MessageAttributes))
+		}
+		m.Attributes[name] = *value.StringValue
+	}
+	return m
+}
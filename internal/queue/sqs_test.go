@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing/tracingtest"
+)
+
+func TestFromSQS(t *testing.T) {
+	sent := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	msg := fromSQS(types.Message{
+		MessageId:     aws.String("m1"),
+		Body:          aws.String(`{"job_id":"abc"}`),
+		ReceiptHandle: aws.String("r1"),
+		Attributes: map[string]string{
+			string(types.MessageSystemAttributeNameApproximateReceiveCount): "3",
+			string(types.MessageSystemAttributeNameSentTimestamp):           "1714564800000",
+		},
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"traceparent": {DataType: aws.String("String"), StringValue: aws.String("00-abc-def-01")},
+			"blob":        {DataType: aws.String("Binary"), BinaryValue: []byte{1}},
+		},
+	})
+
+	assert.Equal(t, "m1", msg.ID)
+	assert.Equal(t, "r1", msg.ReceiptHandle)
+	assert.Equal(t, 3, msg.ReceiveCount)
+	assert.Equal(t, sent, msg.EnqueuedAt)
+	assert.Equal(t, map[string]string{"traceparent": "00-abc-def-01"}, msg.Attributes)
+
+	jobMsg, err := msg.JobMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", jobMsg.JobID)
+
+	letter := toDeadLetter(msg)
+	assert.Equal(t, "abc", letter.JobID)
+	assert.Equal(t, 3, letter.ReceiveCount)
+	assert.Equal(t, sent, letter.SentAt)
+}
+
+func TestFromSQS_MissingAttributes(t *testing.T) {
+	msg := fromSQS(types.Message{MessageId: aws.String("m1"), Body: aws.String("not json")})
+
+	assert.Zero(t, msg.ReceiveCount)
+	assert.True(t, msg.EnqueuedAt.IsZero())
+	assert.Nil(t, msg.Attributes)
+
+	_, err := msg.JobMessage()
+	assert.Error(t, err)
+	assert.Empty(t, toDeadLetter(msg).JobID)
+}
+
+func TestTraceAttributes(t *testing.T) {
+	tracingtest.Record(t)
+
+	assert.Nil(t, traceAttributes(context.Background()))
+
+	ctx, span := tracing.Tracer().Start(context.Background(), "publish")
+	defer span.End()
+
+	// What is sent comes back out of fromSQS as the same trace context
+	attributes := traceAttributes(ctx)
+	require.Contains(t, attributes, "traceparent")
+	assert.Equal(t, "String", aws.ToString(attributes["traceparent"].DataType))
+	msg := fromSQS(types.Message{MessageId: aws.String("m1"), MessageAttributes: attributes})
+	assert.Equal(t, tracing.Inject(ctx), msg.Attributes)
+}
+
+func TestRedriveTarget(t *testing.T) {
+	normal := &SQSClient{queueURL: "https://sqs/jobs", dlqURL: "https://sqs/jobs-dlq"}
+	high := normal.ForQueue("https://sqs/jobs-high")
+	assert.Equal(t, normal.DeadLetterQueueURL(), high.DeadLetterQueueURL())
+
+	// A message sent to the high priority queue goes back there from the
+	// shared DLQ, whichever client redrives it
+	attributes := high.messageAttributes(context.Background())
+	assert.Equal(t, "https://sqs/jobs-high", aws.ToString(attributes[sourceQueueAttribute].StringValue))
+	letter := types.Message{MessageId: aws.String("m1"), MessageAttributes: attributes}
+	assert.Equal(t, "https://sqs/jobs-high", normal.redriveTarget(letter))
+
+	// Messages dead-lettered before they carried their queue go to the main one
+	assert.Equal(t, "https://sqs/jobs", normal.redriveTarget(types.Message{MessageId: aws.String("m2")}))
+}
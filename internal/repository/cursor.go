@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// jobCursor is the position after the last job of a page. It is handed to
+// clients base64-encoded so they treat it as opaque.
+type jobCursor struct {
+	CreatedAt time.Time       `json:"t"`
+	ID        uuid.UUID       `json:"id"`
+	Order     models.JobOrder `json:"o"`
+}
+
+func encodeCursor(job models.Job, order models.JobOrder) string {
+	raw, _ := json.Marshal(jobCursor{CreatedAt: job.CreatedAt, ID: job.ID, Order: order})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a cursor, which must come from a page listed in order
+func decodeCursor(s string, order models.JobOrder) (*jobCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor jobCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == uuid.Nil || cursor.Order != order {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// after reports whether job comes after the cursor in its order
+func (c *jobCursor) after(job models.Job) bool {
+	if c.Order == models.JobOrderOldest {
+		return job.CreatedAt.After(c.CreatedAt) ||
+			(job.CreatedAt.Equal(c.CreatedAt) && job.ID.String() > c.ID.String())
+	}
+	return job.CreatedAt.Before(c.CreatedAt) ||
+		(job.CreatedAt.Equal(c.CreatedAt) && job.ID.String() < c.ID.String())
+}
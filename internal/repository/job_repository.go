@@ -1,17 +1,28 @@
 package repository
 
 import (
+	"context"
 	"errors"
-	
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
-	
+	"gorm.io/gorm/clause"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
 )
 
 var (
-	ErrJobNotFound = errors.New("job not found")
-	ErrInvalidID   = errors.New("invalid job ID")
+	ErrJobNotFound      = errors.New("job not found")
+	ErrInvalidID        = errors.New("invalid job ID")
+	ErrJobNotClaimable  = errors.New("job is not claimable")
+	ErrLeaseLost        = errors.New("job lease lost")
+	ErrJobFinished      = errors.New("job already finished")
+	ErrJobNotRerunnable = errors.New("only failed or cancelled jobs can be retried")
+	ErrJobNotRedrivable = errors.New("job is finished or held by a live worker")
 )
 
 type JobRepository struct {
@@ -22,11 +33,85 @@ func NewJobRepository(db *gorm.DB) *JobRepository {
 	return &JobRepository{db: db}
 }
 
+// WithContext returns a repository running its queries with ctx
+func (r *JobRepository) WithContext(ctx context.Context) interfaces.Repository {
+	return &JobRepository{db: r.db.WithContext(ctx)}
+}
+
+// WithContext returns repo running its queries with ctx if it supports
+// that, and repo itself otherwise
+func WithContext(ctx context.Context, repo interfaces.Repository) interfaces.Repository {
+	if r, ok := repo.(interfaces.ContextRepository); ok {
+		return r.WithContext(ctx)
+	}
+	return repo
+}
+
+// CreateJob inserts the job together with its outbox message so the job is
+// queued by the outbox relay even if the queue is unavailable right now.
 func (r *JobRepository) CreateJob(job *models.Job) error {
 	if job == nil {
 		return errors.New("job cannot be nil")
 	}
-	return r.db.Create(job).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return createJob(tx, job)
+	})
+}
+
+// createJob inserts job with the outbox message that queues it. Scheduled
+// jobs get theirs when PromoteScheduledJobs finds them nearly due.
+func createJob(tx *gorm.DB, job *models.Job) error {
+	if err := tx.Create(job).Error; err != nil {
+		return err
+	}
+	if job.Status == models.JobStatusScheduled {
+		return nil
+	}
+	return tx.Create(&models.OutboxMessage{
+		JobID:        job.ID,
+		RunAt:        job.RunAt,
+		Priority:     job.Priority,
+		TraceContext: job.TraceContext,
+	}).Error
+}
+
+// PromoteScheduledJobs makes up to limit scheduled jobs due by dueBy pending
+// and queues them, their run_at delaying delivery. It returns how many it
+// promoted.
+func (r *JobRepository) PromoteScheduledJobs(dueBy time.Time, limit int) (int, error) {
+	var promoted []models.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`
+			UPDATE jobs SET status = ?, updated_at = ?
+			WHERE id IN (
+				SELECT id FROM jobs
+				WHERE status = ? AND run_at <= ?
+				ORDER BY run_at
+				LIMIT ?
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, run_at, priority, trace_context`,
+			models.JobStatusPending, time.Now(), models.JobStatusScheduled, dueBy, limit,
+		).Scan(&promoted).Error
+		if err != nil || len(promoted) == 0 {
+			return err
+		}
+
+		messages := make([]models.OutboxMessage, len(promoted))
+		for i, job := range promoted {
+			messages[i] = models.OutboxMessage{
+				JobID:        job.ID,
+				RunAt:        job.RunAt,
+				Priority:     job.Priority,
+				TraceContext: job.TraceContext,
+			}
+		}
+		return tx.Create(&messages).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to promote scheduled jobs: %w", err)
+	}
+	return len(promoted), nil
 }
 
 func (r *JobRepository) GetJob(id string) (*models.Job, error) {
@@ -46,35 +131,340 @@ func (r *JobRepository) GetJob(id string) (*models.Job, error) {
 	return &job, nil
 }
 
+// UpdateJob saves job. If that finishes the job, its webhook delivery is
+// written in the same transaction.
 func (r *JobRepository) UpdateJob(job *models.Job) error {
 	if job == nil {
 		return errors.New("job cannot be nil")
 	}
-	return r.db.Save(job).Error
+	if job.CallbackURL == "" || !job.IsFinished() {
+		return r.db.Save(job).Error
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var previous models.Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("status").First(&previous, "id = ?", job.ID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Save(job).Error; err != nil {
+			return err
+		}
+		if previous.IsFinished() {
+			return nil
+		}
+		return createWebhookDelivery(tx, job)
+	})
 }
 
-func (r *JobRepository) ListJobs(status string, limit int) ([]models.Job, error) {
-	var jobs []models.Job
-	
-	query := r.db.Order("created_at DESC")
-	
-	if limit > 0 {
-		query = query.Limit(limit)
-	} else {
-		query = query.Limit(100) // default limit
+// createWebhookDelivery records the notification that job finished, if it
+// has a callback URL
+func createWebhookDelivery(tx *gorm.DB, job *models.Job) error {
+	delivery, err := models.NewWebhookDelivery(job, time.Now())
+	if err != nil || delivery == nil {
+		return err
 	}
-	
+	return tx.Create(delivery).Error
+}
+
+func (r *JobRepository) ListJobs(status string, limit int) ([]models.Job, error) {
+	filter := models.JobFilter{Limit: limit}
 	if status != "" {
-		query = query.Where("status = ?", status)
+		filter.Statuses = []models.JobStatus{models.JobStatus(status)}
 	}
-	
-	if err := query.Find(&jobs).Error; err != nil {
+	page, err := r.FindJobs(filter)
+	if err != nil {
 		return nil, err
 	}
-	
-	return jobs, nil
+	return page.Jobs, nil
+}
+
+// FindJobs returns a page of the jobs matching filter, ordered by
+// (created_at, id) so pages stay stable while new jobs are created
+func (r *JobRepository) FindJobs(filter models.JobFilter) (*models.JobPage, error) {
+	filter = filter.Normalized()
+	cursor, err := decodeCursor(filter.Cursor, filter.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.db.Model(&models.Job{})
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		query = query.Where("(data::text ILIKE ? OR error ILIKE ?)", pattern, pattern)
+	}
+
+	page := &models.JobPage{}
+	if filter.IncludeTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, fmt.Errorf("failed to count jobs: %w", err)
+		}
+		page.Total = &total
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Order == models.JobOrderOldest {
+		direction, comparison = "ASC", ">"
+	}
+	if cursor != nil {
+		query = query.Where("(created_at, id) "+comparison+" (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	// One extra row tells whether there is a next page
+	var jobs []models.Job
+	if err := query.Order("created_at " + direction).Order("id " + direction).
+		Limit(filter.Limit + 1).Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	if len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+		page.NextCursor = encodeCursor(jobs[len(jobs)-1], filter.Order)
+	}
+	page.Jobs = jobs
+	return page, nil
 }
 
+// likeEscaper escapes LIKE wildcards so searches match text literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 func (r *JobRepository) GetPendingJobs(limit int) ([]models.Job, error) {
 	return r.ListJobs(string(models.JobStatusPending), limit)
-}
\ No newline at end of file
+}
+
+// ClaimJob moves the job to processing in a single conditional UPDATE, so
+// of two workers holding the same job ID only one gets it. It fails with
+// ErrJobNotClaimable if the job is finished or leased by a live worker.
+// Lease times come from the database clock, like those of LeaseRepository,
+// so workers whose clocks drift apart still agree on expiry.
+func (r *JobRepository) ClaimJob(id string, workerID string, lease time.Duration) (*models.Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var job models.Job
+	result := r.db.Model(&job).
+		Clauses(clause.Returning{}).
+		Where("id = ?", jobID).
+		Where("(status IN ? OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < now())))",
+			[]models.JobStatus{models.JobStatusPending, models.JobStatusRetrying}, models.JobStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":           models.JobStatusProcessing,
+			"worker_id":        workerID,
+			"lease_expires_at": leaseExpiry(lease),
+			"attempts":         gorm.Expr("attempts + 1"),
+			"updated_at":       gorm.Expr("now()"),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.GetJob(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrJobNotClaimable
+	}
+	return &job, nil
+}
+
+// leaseExpiry is when a lease taken now for lease runs out, by the database
+// clock
+func leaseExpiry(lease time.Duration) clause.Expr {
+	return gorm.Expr("now() + make_interval(secs => ?)", lease.Seconds())
+}
+
+func (r *JobRepository) RenewJobLease(id string, workerID string, lease time.Duration) error {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result := r.db.Model(&models.Job{}).
+		Where("id = ? AND worker_id = ? AND status = ?", jobID, workerID, models.JobStatusProcessing).
+		Update("lease_expires_at", leaseExpiry(lease))
+	if result.Error != nil {
+		return fmt.Errorf("failed to renew job lease: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ResetJobForRedrive makes a job whose message is being redriven from the
+// dead-letter queue pending again, clearing its error and any expired lease,
+// in a conditional UPDATE. It fails with ErrJobNotRedrivable if the job
+// completed, was cancelled or is held by a live worker, whose writes it must
+// not overwrite.
+func (r *JobRepository) ResetJobForRedrive(id string) error {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result := r.db.Model(&models.Job{}).
+		Where("id = ?", jobID).
+		Where("(status IN ? OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < now())))",
+			[]models.JobStatus{models.JobStatusPending, models.JobStatusRetrying, models.JobStatusFailed}, models.JobStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":           models.JobStatusPending,
+			"error":            "",
+			"worker_id":        "",
+			"lease_expires_at": nil,
+			"updated_at":       gorm.Expr("now()"),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to reset job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.GetJob(id); err != nil {
+			return err
+		}
+		return ErrJobNotRedrivable
+	}
+	return nil
+}
+
+// FinishJob saves the outcome of job's attempt in a conditional UPDATE that
+// only applies while job.WorkerID still holds the job, so a worker whose
+// lease ran out can't overwrite the job after another worker or a
+// cancellation took it over. It fails with ErrLeaseLost otherwise. Only the
+// columns an attempt owns are written, so a cancellation requested meanwhile
+// is kept. If that finishes the job, its webhook delivery is written in the
+// same transaction.
+// finishColumns are the columns FinishJob writes
+var finishColumns = []string{
+	"status", "result", "error", "attempts", "attempt_history",
+	"worker_id", "lease_expires_at", "updated_at",
+}
+
+func (r *JobRepository) FinishJob(job *models.Job) error {
+	if job == nil {
+		return errors.New("job cannot be nil")
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(job).
+			Where("worker_id = ? AND status = ?", job.WorkerID, models.JobStatusProcessing).
+			Select(finishColumns).
+			Updates(job)
+		if result.Error != nil {
+			return fmt.Errorf("failed to finish job: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrLeaseLost
+		}
+		if !job.IsFinished() {
+			return nil
+		}
+		return createWebhookDelivery(tx, job)
+	})
+}
+
+// CancelJob cancels the job straight away if no worker holds it. For a job
+// a worker is running it records the request instead; the worker notices,
+// cancels the handler's context and marks the job cancelled. Finished jobs
+// fail with ErrJobFinished.
+func (r *JobRepository) CancelJob(id string) (*models.Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var job models.Job
+	var cancelled bool
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&job).
+			Clauses(clause.Returning{}).
+			Where("id = ?", jobID).
+			Where("(status IN ? OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < now())))",
+				[]models.JobStatus{models.JobStatusPending, models.JobStatusRetrying, models.JobStatusScheduled}, models.JobStatusProcessing).
+			Updates(map[string]interface{}{
+				"status":           models.JobStatusCancelled,
+				"lease_expires_at": nil,
+				"updated_at":       gorm.Expr("now()"),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		cancelled = true
+		return createWebhookDelivery(tx, &job)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if cancelled {
+		return &job, nil
+	}
+
+	result := r.db.Model(&job).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ? AND cancel_requested_at IS NULL", jobID, models.JobStatusProcessing).
+		Updates(map[string]interface{}{
+			"cancel_requested_at": gorm.Expr("now()"),
+			"updated_at":          gorm.Expr("now()"),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to request job cancellation: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return &job, nil
+	}
+
+	found, err := r.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if found.IsFinished() {
+		return found, ErrJobFinished
+	}
+	// Cancellation was already requested
+	return found, nil
+}
+
+// RetryJob re-runs a failed or cancelled job as a new job linked to it by
+// ParentID, leaving the original and its history untouched. Like CreateJob
+// it writes an outbox message, so the relay queues the new job.
+func (r *JobRepository) RetryJob(id string, retriedBy string) (*models.Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var rerun *models.Job
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		var job models.Job
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, "id = ?", jobID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrJobNotFound
+			}
+			return err
+		}
+		if !job.Rerunnable() {
+			return ErrJobNotRerunnable
+		}
+
+		rerun = job.Rerun(retriedBy, time.Now())
+		if err := tx.Create(rerun).Error; err != nil {
+			return fmt.Errorf("failed to create job: %w", err)
+		}
+		return tx.Create(&models.OutboxMessage{JobID: rerun.ID, Priority: rerun.Priority}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rerun, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+var ErrLeaseNotFound = errors.New("lease not found")
+
+type LeaseRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaseRepository(db *gorm.DB) *LeaseRepository {
+	return &LeaseRepository{db: db}
+}
+
+// AcquireLease takes the named lease for holder if it is free or expired, or
+// renews it if holder already has it. It returns the lease as it now stands
+// and whether holder is the one holding it. Times come from the database
+// clock, so instances whose clocks drift apart still agree on expiry.
+func (r *LeaseRepository) AcquireLease(name, holder string, ttl time.Duration) (*models.Lease, bool, error) {
+	var leases []models.Lease
+	err := r.db.Raw(`
+		INSERT INTO leases (name, holder, acquired_at, renewed_at, expires_at)
+		VALUES (?, ?, now(), now(), now() + make_interval(secs => ?))
+		ON CONFLICT (name) DO UPDATE SET
+			holder = EXCLUDED.holder,
+			acquired_at = CASE WHEN leases.holder = EXCLUDED.holder THEN leases.acquired_at ELSE EXCLUDED.acquired_at END,
+			renewed_at = EXCLUDED.renewed_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < now()
+		RETURNING *`,
+		name, holder, ttl.Seconds(),
+	).Scan(&leases).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	if len(leases) == 1 {
+		return &leases[0], true, nil
+	}
+
+	// Someone else holds it
+	lease, err := r.GetLease(name)
+	if err != nil {
+		return nil, false, err
+	}
+	return lease, false, nil
+}
+
+// ReleaseLease gives up the named lease if holder has it, so another
+// instance can take over without waiting for it to expire
+func (r *LeaseRepository) ReleaseLease(name, holder string) error {
+	err := r.db.Where("name = ? AND holder = ?", name, holder).Delete(&models.Lease{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+func (r *LeaseRepository) GetLease(name string) (*models.Lease, error) {
+	var lease models.Lease
+	if err := r.db.First(&lease, "name = ?", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeaseNotFound
+		}
+		return nil, err
+	}
+	return &lease, nil
+}
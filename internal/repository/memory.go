@@ -0,0 +1,769 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+// MemoryRepository is an in-memory implementation of the job repository and
+// outbox, for tests and single-process runs. It stores copies so callers
+// can't mutate stored jobs without calling UpdateJob, like a real database.
+type MemoryRepository struct {
+	mu        sync.RWMutex
+	jobs      map[uuid.UUID]models.Job
+	outbox    map[uuid.UUID]models.OutboxMessage
+	schedules map[uuid.UUID]models.Schedule
+	runs      map[uuid.UUID][]models.ScheduleRun
+	leases    map[string]models.Lease
+	webhooks  map[uuid.UUID]models.WebhookDelivery
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		jobs:      make(map[uuid.UUID]models.Job),
+		outbox:    make(map[uuid.UUID]models.OutboxMessage),
+		schedules: make(map[uuid.UUID]models.Schedule),
+		runs:      make(map[uuid.UUID][]models.ScheduleRun),
+		leases:    make(map[string]models.Lease),
+		webhooks:  make(map[uuid.UUID]models.WebhookDelivery),
+	}
+}
+
+func (r *MemoryRepository) CreateJob(job *models.Job) error {
+	if job == nil {
+		return errors.New("job cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.createJob(job)
+}
+
+// createJob stores job with its outbox message. Callers hold r.mu.
+func (r *MemoryRepository) createJob(job *models.Job) error {
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	if _, exists := r.jobs[job.ID]; exists {
+		return errors.New("duplicate job ID")
+	}
+	if job.Status == "" {
+		job.Status = models.JobStatusPending
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = models.DefaultMaxAttempts
+	}
+	if job.Priority == "" {
+		job.Priority = models.JobPriorityNormal
+	}
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	r.jobs[job.ID] = copyJob(*job)
+
+	if job.Status != models.JobStatusScheduled {
+		r.enqueue(job, now)
+	}
+	return nil
+}
+
+// enqueue writes the outbox message that queues job. Callers hold r.mu.
+func (r *MemoryRepository) enqueue(job *models.Job, now time.Time) {
+	msg := models.OutboxMessage{ID: uuid.New(), JobID: job.ID, NextAttemptAt: now, CreatedAt: now, UpdatedAt: now, Priority: job.Priority}
+	msg.TraceContext = job.TraceContext
+	if job.RunAt != nil {
+		runAt := *job.RunAt
+		msg.RunAt = &runAt
+	}
+	r.outbox[msg.ID] = msg
+}
+
+func (r *MemoryRepository) PromoteScheduledJobs(dueBy time.Time, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.Job
+	for _, job := range r.jobs {
+		if job.Status == models.JobStatusScheduled && job.RunAt != nil && !job.RunAt.After(dueBy) {
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].RunAt.Before(*due[j].RunAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	now := time.Now()
+	for _, job := range due {
+		job.Status = models.JobStatusPending
+		job.UpdatedAt = now
+		r.jobs[job.ID] = job
+		r.enqueue(&job, now)
+	}
+	return len(due), nil
+}
+
+func (r *MemoryRepository) GetJob(id string) (*models.Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := copyJob(job)
+	return &copied, nil
+}
+
+func (r *MemoryRepository) UpdateJob(job *models.Job) error {
+	if job == nil {
+		return errors.New("job cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Save semantics: upsert, keeping the original creation time
+	existing, ok := r.jobs[job.ID]
+	if ok {
+		job.CreatedAt = existing.CreatedAt
+	} else if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+	job.UpdatedAt = time.Now()
+	r.jobs[job.ID] = copyJob(*job)
+
+	if job.IsFinished() && !existing.IsFinished() {
+		return r.recordWebhook(job)
+	}
+	return nil
+}
+
+// recordWebhook stores the delivery announcing that job finished, if it has
+// a callback URL. Callers hold r.mu.
+func (r *MemoryRepository) recordWebhook(job *models.Job) error {
+	delivery, err := models.NewWebhookDelivery(job, time.Now())
+	if err != nil || delivery == nil {
+		return err
+	}
+	r.webhooks[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *MemoryRepository) ListJobs(status string, limit int) ([]models.Job, error) {
+	filter := models.JobFilter{Limit: limit}
+	if status != "" {
+		filter.Statuses = []models.JobStatus{models.JobStatus(status)}
+	}
+	page, err := r.FindJobs(filter)
+	if err != nil {
+		return nil, err
+	}
+	return page.Jobs, nil
+}
+
+func (r *MemoryRepository) FindJobs(filter models.JobFilter) (*models.JobPage, error) {
+	filter = filter.Normalized()
+	cursor, err := decodeCursor(filter.Cursor, filter.Order)
+	if err != nil {
+		return nil, err
+	}
+	search := strings.ToLower(filter.Search)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := make([]models.Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		switch {
+		case len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, job.Status),
+			len(filter.Types) > 0 && !slices.Contains(filter.Types, job.Type),
+			filter.CreatedAfter != nil && job.CreatedAt.Before(*filter.CreatedAfter),
+			filter.CreatedBefore != nil && !job.CreatedAt.Before(*filter.CreatedBefore),
+			search != "" && !strings.Contains(strings.ToLower(string(job.Data)), search) &&
+				!strings.Contains(strings.ToLower(job.Error), search):
+			continue
+		}
+		matched = append(matched, job)
+	}
+
+	page := &models.JobPage{}
+	if filter.IncludeTotal {
+		total := int64(len(matched))
+		page.Total = &total
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt) == (filter.Order == models.JobOrderOldest)
+		}
+		return (a.ID.String() < b.ID.String()) == (filter.Order == models.JobOrderOldest)
+	})
+
+	page.Jobs = make([]models.Job, 0, filter.Limit)
+	for _, job := range matched {
+		if cursor != nil && !cursor.after(job) {
+			continue
+		}
+		if len(page.Jobs) == filter.Limit {
+			page.NextCursor = encodeCursor(page.Jobs[len(page.Jobs)-1], filter.Order)
+			break
+		}
+		page.Jobs = append(page.Jobs, copyJob(job))
+	}
+	return page, nil
+}
+
+func (r *MemoryRepository) GetPendingJobs(limit int) ([]models.Job, error) {
+	return r.ListJobs(string(models.JobStatusPending), limit)
+}
+
+func (r *MemoryRepository) ClaimJob(id string, workerID string, lease time.Duration) (*models.Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	now := time.Now()
+	if !job.Claimable(now) {
+		return nil, ErrJobNotClaimable
+	}
+
+	leaseExpiresAt := now.Add(lease)
+	job.Status = models.JobStatusProcessing
+	job.WorkerID = workerID
+	job.LeaseExpiresAt = &leaseExpiresAt
+	job.Attempts++
+	job.UpdatedAt = now
+	r.jobs[jobID] = job
+
+	copied := copyJob(job)
+	return &copied, nil
+}
+
+func (r *MemoryRepository) RenewJobLease(id string, workerID string, lease time.Duration) error {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobID]
+	if !ok || job.WorkerID != workerID || job.Status != models.JobStatusProcessing {
+		return ErrLeaseLost
+	}
+	leaseExpiresAt := time.Now().Add(lease)
+	job.LeaseExpiresAt = &leaseExpiresAt
+	r.jobs[jobID] = job
+	return nil
+}
+
+func (r *MemoryRepository) ResetJobForRedrive(id string) error {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return ErrJobNotFound
+	}
+	now := time.Now()
+	if !job.Redrivable(now) {
+		return ErrJobNotRedrivable
+	}
+	job.Status = models.JobStatusPending
+	job.Error = ""
+	job.WorkerID = ""
+	job.LeaseExpiresAt = nil
+	job.UpdatedAt = now
+	r.jobs[jobID] = job
+	return nil
+}
+
+func (r *MemoryRepository) FinishJob(job *models.Job) error {
+	if job == nil {
+		return errors.New("job cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.jobs[job.ID]
+	if !ok || existing.WorkerID != job.WorkerID || existing.Status != models.JobStatusProcessing {
+		return ErrLeaseLost
+	}
+	// Like the conditional UPDATE, only what the attempt owns is written
+	finished := copyJob(*job)
+	existing.Status = finished.Status
+	existing.Result = finished.Result
+	existing.Error = finished.Error
+	existing.Attempts = finished.Attempts
+	existing.AttemptHistory = finished.AttemptHistory
+	existing.LeaseExpiresAt = finished.LeaseExpiresAt
+	existing.UpdatedAt = time.Now()
+	r.jobs[job.ID] = existing
+	job.UpdatedAt = existing.UpdatedAt
+
+	if job.IsFinished() {
+		return r.recordWebhook(job)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) CancelJob(id string) (*models.Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	now := time.Now()
+	switch {
+	case job.IsFinished():
+		copied := copyJob(job)
+		return &copied, ErrJobFinished
+	case job.Claimable(now) || job.Status == models.JobStatusScheduled:
+		job.Status = models.JobStatusCancelled
+		job.LeaseExpiresAt = nil
+		job.UpdatedAt = now
+		if err := r.recordWebhook(&job); err != nil {
+			return nil, err
+		}
+	case job.CancelRequestedAt == nil:
+		job.CancelRequestedAt = &now
+		job.UpdatedAt = now
+	}
+	r.jobs[jobID] = job
+
+	copied := copyJob(job)
+	return &copied, nil
+}
+
+func (r *MemoryRepository) RetryJob(id string, retriedBy string) (*models.Job, error) {
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	if !job.Rerunnable() {
+		return nil, ErrJobNotRerunnable
+	}
+
+	now := time.Now()
+	rerun := job.Rerun(retriedBy, now)
+	rerun.ID = uuid.New()
+	rerun.CreatedAt = now
+	rerun.UpdatedAt = now
+	r.jobs[rerun.ID] = copyJob(*rerun)
+	r.enqueue(rerun, now)
+	return rerun, nil
+}
+
+func (r *MemoryRepository) ClaimOutboxMessages(limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	due := make([]models.OutboxMessage, 0)
+	for _, msg := range r.outbox {
+		if msg.DeliveredAt == nil && !msg.NextAttemptAt.After(now) {
+			due = append(due, msg)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].Attempts++
+		due[i].NextAttemptAt = now.Add(lease)
+		due[i].UpdatedAt = now
+		r.outbox[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *MemoryRepository) MarkOutboxDelivered(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg, ok := r.outbox[id]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	msg.DeliveredAt = &now
+	msg.LastError = ""
+	r.outbox[id] = msg
+	return nil
+}
+
+func (r *MemoryRepository) MarkOutboxFailed(id uuid.UUID, reason string, nextAttemptAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg, ok := r.outbox[id]
+	if !ok {
+		return nil
+	}
+	msg.LastError = reason
+	msg.NextAttemptAt = nextAttemptAt
+	r.outbox[id] = msg
+	return nil
+}
+
+func (r *MemoryRepository) CountUndeliveredOutbox() (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, msg := range r.outbox {
+		if msg.DeliveredAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryRepository) CreateSchedule(schedule *models.Schedule) error {
+	if schedule == nil {
+		return errors.New("schedule cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.scheduleNameTaken(schedule.Name, uuid.Nil) {
+		return ErrDuplicateScheduleName
+	}
+	if schedule.ID == uuid.Nil {
+		schedule.ID = uuid.New()
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	schedule.SetDefaults()
+	now := time.Now()
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	r.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+func (r *MemoryRepository) GetSchedule(id string) (*models.Schedule, error) {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedule, ok := r.schedules[scheduleID]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	return &schedule, nil
+}
+
+func (r *MemoryRepository) ListSchedules() ([]models.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := make([]models.Schedule, 0, len(r.schedules))
+	for _, schedule := range r.schedules {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Name < schedules[j].Name
+	})
+	return schedules, nil
+}
+
+func (r *MemoryRepository) UpdateSchedule(schedule *models.Schedule) error {
+	if schedule == nil {
+		return errors.New("schedule cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.schedules[schedule.ID]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	if r.scheduleNameTaken(schedule.Name, schedule.ID) {
+		return ErrDuplicateScheduleName
+	}
+	schedule.CreatedAt = existing.CreatedAt
+	schedule.UpdatedAt = time.Now()
+	r.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+func (r *MemoryRepository) DeleteSchedule(id string) error {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schedules[scheduleID]; !ok {
+		return ErrScheduleNotFound
+	}
+	delete(r.schedules, scheduleID)
+	delete(r.runs, scheduleID)
+	return nil
+}
+
+func (r *MemoryRepository) RecordScheduleRun(run *models.ScheduleRun, job *models.Job) error {
+	if run == nil {
+		return errors.New("schedule run cannot be nil")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.runs[run.ScheduleID] {
+		if existing.PlannedAt.Equal(run.PlannedAt) {
+			return ErrDuplicateScheduleRun
+		}
+	}
+	if job != nil {
+		if err := r.createJob(job); err != nil {
+			return err
+		}
+		run.JobID = &job.ID
+	}
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+	run.CreatedAt = time.Now()
+	r.runs[run.ScheduleID] = append(r.runs[run.ScheduleID], *run)
+	return nil
+}
+
+func (r *MemoryRepository) ListScheduleRuns(scheduleID string, limit int) ([]models.ScheduleRun, error) {
+	id, err := uuid.Parse(scheduleID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runs := append([]models.ScheduleRun(nil), r.runs[id]...)
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].PlannedAt.After(runs[j].PlannedAt)
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// scheduleNameTaken reports whether a schedule other than except has name.
+// Callers hold r.mu.
+func (r *MemoryRepository) scheduleNameTaken(name string, except uuid.UUID) bool {
+	for id, schedule := range r.schedules {
+		if id != except && schedule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryRepository) AcquireLease(name, holder string, ttl time.Duration) (*models.Lease, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	lease, ok := r.leases[name]
+	if ok && lease.Holder != holder && lease.Active(now) {
+		return &lease, false, nil
+	}
+	if !ok || lease.Holder != holder {
+		lease = models.Lease{Name: name, Holder: holder, AcquiredAt: now}
+	}
+	lease.RenewedAt = now
+	lease.ExpiresAt = now.Add(ttl)
+	r.leases[name] = lease
+	return &lease, true, nil
+}
+
+func (r *MemoryRepository) ReleaseLease(name, holder string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[name]; ok && lease.Holder == holder {
+		delete(r.leases, name)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) GetLease(name string) (*models.Lease, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lease, ok := r.leases[name]
+	if !ok {
+		return nil, ErrLeaseNotFound
+	}
+	return &lease, nil
+}
+
+func (r *MemoryRepository) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var due []models.WebhookDelivery
+	for _, delivery := range r.webhooks {
+		if delivery.Status == models.WebhookStatusPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].Attempts++
+		due[i].NextAttemptAt = now.Add(lease)
+		due[i].UpdatedAt = now
+		r.webhooks[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *MemoryRepository) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery.UpdatedAt = time.Now()
+	r.webhooks[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *MemoryRepository) ListWebhookDeliveries(filter models.WebhookFilter) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range r.webhooks {
+		if filter.JobID != nil && delivery.JobID != *filter.JobID {
+			continue
+		}
+		if filter.Status != "" && delivery.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+	if filter.Limit > 0 && len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryRepository) ReplayWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	deliveryID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.webhooks[deliveryID]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	replay := delivery.Replay(time.Now())
+	r.webhooks[replay.ID] = *replay
+	return replay, nil
+}
+
+// copyJob detaches the slices and pointers a caller could otherwise share
+// with the stored job
+func copyJob(job models.Job) models.Job {
+	job.Data = append(models.JSON(nil), job.Data...)
+	if job.Result != nil {
+		result := *job.Result
+		job.Result = &result
+	}
+	if job.AttemptHistory != nil {
+		job.AttemptHistory = append([]models.JobAttempt(nil), job.AttemptHistory...)
+	}
+	if job.LeaseExpiresAt != nil {
+		leaseExpiresAt := *job.LeaseExpiresAt
+		job.LeaseExpiresAt = &leaseExpiresAt
+	}
+	if job.CancelRequestedAt != nil {
+		cancelRequestedAt := *job.CancelRequestedAt
+		job.CancelRequestedAt = &cancelRequestedAt
+	}
+	if job.ParentID != nil {
+		parentID := *job.ParentID
+		job.ParentID = &parentID
+	}
+	if job.RetriedAt != nil {
+		retriedAt := *job.RetriedAt
+		job.RetriedAt = &retriedAt
+	}
+	if job.RunAt != nil {
+		runAt := *job.RunAt
+		job.RunAt = &runAt
+	}
+	return job
+}
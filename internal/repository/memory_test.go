@@ -0,0 +1,536 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+func TestMemoryRepository_Jobs(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	job := &models.Job{Type: "data-processing", Data: models.JSONString("input")}
+	require.NoError(t, repo.CreateJob(job))
+	assert.NotEqual(t, uuid.Nil, job.ID)
+	assert.Equal(t, models.JobStatusPending, job.Status)
+	assert.Equal(t, models.DefaultMaxAttempts, job.MaxAttempts)
+
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JSONString("input"), found.Data)
+
+	// Mutating a returned job doesn't change the stored one
+	found.Status = models.JobStatusCompleted
+	found.AttemptHistory = append(found.AttemptHistory, models.JobAttempt{Attempt: 1})
+	again, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, again.Status)
+	assert.Empty(t, again.AttemptHistory)
+
+	require.NoError(t, repo.UpdateJob(found))
+	again, err = repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, again.Status)
+	assert.Len(t, again.AttemptHistory, 1)
+
+	_, err = repo.GetJob(uuid.New().String())
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = repo.GetJob("not-a-uuid")
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestMemoryRepository_ListJobs(t *testing.T) {
+	repo := NewMemoryRepository()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.CreateJob(&models.Job{Type: "cleanup"}))
+		time.Sleep(time.Millisecond)
+	}
+	completed := &models.Job{Type: "cleanup", Status: models.JobStatusCompleted}
+	require.NoError(t, repo.CreateJob(completed))
+
+	all, err := repo.ListJobs("", 10)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+	assert.Equal(t, completed.ID, all[0].ID, "newest first")
+
+	pending, err := repo.GetPendingJobs(2)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+}
+
+func TestMemoryRepository_FindJobs(t *testing.T) {
+	repo := NewMemoryRepository()
+	var created []*models.Job
+	for i := 0; i < 5; i++ {
+		job := &models.Job{Type: "cleanup", Data: models.JSONString(fmt.Sprintf("batch %d", i))}
+		if i%2 == 1 {
+			job.Type = "report"
+		}
+		require.NoError(t, repo.CreateJob(job))
+		created = append(created, job)
+		time.Sleep(time.Millisecond)
+	}
+	created[4].Status = models.JobStatusFailed
+	created[4].Error = "Upstream TIMEOUT"
+	require.NoError(t, repo.UpdateJob(created[4]))
+
+	// Walk every page oldest first
+	var seen []uuid.UUID
+	filter := models.JobFilter{Order: models.JobOrderOldest, Limit: 2, IncludeTotal: true}
+	for {
+		page, err := repo.FindJobs(filter)
+		require.NoError(t, err)
+		require.NotNil(t, page.Total)
+		assert.Equal(t, int64(5), *page.Total)
+		for _, job := range page.Jobs {
+			seen = append(seen, job.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+	require.Len(t, seen, 5)
+	for i, job := range created {
+		assert.Equal(t, job.ID, seen[i])
+	}
+
+	// A cursor only continues a listing in the same order
+	_, err := repo.FindJobs(models.JobFilter{Cursor: filter.Cursor})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = repo.FindJobs(models.JobFilter{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	tests := []struct {
+		name   string
+		filter models.JobFilter
+		want   []*models.Job
+	}{
+		{"type", models.JobFilter{Types: []string{"report"}}, []*models.Job{created[3], created[1]}},
+		{"statuses", models.JobFilter{Statuses: []models.JobStatus{models.JobStatusFailed, models.JobStatusCancelled}}, []*models.Job{created[4]}},
+		{"search data", models.JobFilter{Search: "BATCH 2"}, []*models.Job{created[2]}},
+		{"search error", models.JobFilter{Search: "timeout"}, []*models.Job{created[4]}},
+		{"created range", models.JobFilter{CreatedAfter: &created[1].CreatedAt, CreatedBefore: &created[3].CreatedAt}, []*models.Job{created[2], created[1]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.FindJobs(tt.filter)
+			require.NoError(t, err)
+			require.Len(t, page.Jobs, len(tt.want))
+			for i, job := range tt.want {
+				assert.Equal(t, job.ID, page.Jobs[i].ID)
+			}
+			assert.Empty(t, page.NextCursor)
+			assert.Nil(t, page.Total)
+		})
+	}
+}
+
+func TestMemoryRepository_Outbox(t *testing.T) {
+	repo := NewMemoryRepository()
+	job := &models.Job{Type: "cleanup", Priority: models.JobPriorityLow}
+	require.NoError(t, repo.CreateJob(job))
+
+	count, err := repo.CountUndeliveredOutbox()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	claimed, err := repo.ClaimOutboxMessages(10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, job.ID, claimed[0].JobID)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.Equal(t, models.JobPriorityLow, claimed[0].Priority)
+
+	// Leased messages aren't claimed again until the lease expires
+	again, err := repo.ClaimOutboxMessages(10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	id := claimed[0].ID
+	require.NoError(t, repo.MarkOutboxFailed(id, "boom", time.Now()))
+	claimed, err = repo.ClaimOutboxMessages(10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, 2, claimed[0].Attempts)
+	assert.Equal(t, "boom", claimed[0].LastError)
+
+	require.NoError(t, repo.MarkOutboxDelivered(id))
+	count, err = repo.CountUndeliveredOutbox()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestMemoryRepository_ClaimJob(t *testing.T) {
+	repo := NewMemoryRepository()
+	job := &models.Job{Type: "cleanup"}
+	require.NoError(t, repo.CreateJob(job))
+
+	claimed, err := repo.ClaimJob(job.ID.String(), "worker-a", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusProcessing, claimed.Status)
+	assert.Equal(t, "worker-a", claimed.WorkerID)
+	assert.Equal(t, 1, claimed.Attempts)
+	require.NotNil(t, claimed.LeaseExpiresAt)
+
+	// A second worker can't take a job under a live lease
+	_, err = repo.ClaimJob(job.ID.String(), "worker-b", time.Minute)
+	assert.ErrorIs(t, err, ErrJobNotClaimable)
+	assert.ErrorIs(t, repo.RenewJobLease(job.ID.String(), "worker-b", time.Minute), ErrLeaseLost)
+	assert.NoError(t, repo.RenewJobLease(job.ID.String(), "worker-a", time.Minute))
+
+	// Once the lease runs out the job can be reclaimed
+	require.NoError(t, repo.RenewJobLease(job.ID.String(), "worker-a", -time.Second))
+	reclaimed, err := repo.ClaimJob(job.ID.String(), "worker-b", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "worker-b", reclaimed.WorkerID)
+	assert.Equal(t, 2, reclaimed.Attempts)
+	assert.ErrorIs(t, repo.RenewJobLease(job.ID.String(), "worker-a", time.Minute), ErrLeaseLost)
+
+	// Finished jobs are never claimable
+	reclaimed.Status = models.JobStatusCompleted
+	require.NoError(t, repo.UpdateJob(reclaimed))
+	_, err = repo.ClaimJob(job.ID.String(), "worker-a", time.Minute)
+	assert.ErrorIs(t, err, ErrJobNotClaimable)
+
+	_, err = repo.ClaimJob(uuid.New().String(), "worker-a", time.Minute)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestMemoryRepository_FinishJob(t *testing.T) {
+	repo := NewMemoryRepository()
+	job := &models.Job{Type: "cleanup"}
+	require.NoError(t, repo.CreateJob(job))
+
+	claimed, err := repo.ClaimJob(job.ID.String(), "worker-a", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, repo.RenewJobLease(job.ID.String(), "worker-a", -time.Second))
+	_, err = repo.ClaimJob(job.ID.String(), "worker-b", time.Minute)
+	require.NoError(t, err)
+
+	// The worker whose lease ran out can't overwrite the new holder's run
+	claimed.Status = models.JobStatusCompleted
+	assert.ErrorIs(t, repo.FinishJob(claimed), ErrLeaseLost)
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusProcessing, found.Status)
+	assert.Equal(t, "worker-b", found.WorkerID)
+
+	found.Status = models.JobStatusCompleted
+	require.NoError(t, repo.FinishJob(found))
+	found, err = repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, found.Status)
+
+	// Nor can a finished job be finished again
+	assert.ErrorIs(t, repo.FinishJob(found), ErrLeaseLost)
+
+	// A cancellation requested while the job ran survives the worker's
+	// copy, which predates it
+	running := &models.Job{Type: "cleanup"}
+	require.NoError(t, repo.CreateJob(running))
+	claimed, err = repo.ClaimJob(running.ID.String(), "worker-a", time.Minute)
+	require.NoError(t, err)
+	_, err = repo.CancelJob(running.ID.String())
+	require.NoError(t, err)
+	claimed.Status = models.JobStatusCompleted
+	require.NoError(t, repo.FinishJob(claimed))
+	found, err = repo.GetJob(running.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, found.Status)
+	assert.NotNil(t, found.CancelRequestedAt)
+}
+
+func TestMemoryRepository_ResetJobForRedrive(t *testing.T) {
+	repo := NewMemoryRepository()
+	job := &models.Job{Type: "cleanup"}
+	require.NoError(t, repo.CreateJob(job))
+
+	// A live worker's job is left alone
+	_, err := repo.ClaimJob(job.ID.String(), "worker-a", time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, repo.ResetJobForRedrive(job.ID.String()), ErrJobNotRedrivable)
+
+	// Once its lease runs out the job is pending again, with no worker
+	require.NoError(t, repo.RenewJobLease(job.ID.String(), "worker-a", -time.Second))
+	require.NoError(t, repo.ResetJobForRedrive(job.ID.String()))
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, found.Status)
+	assert.Empty(t, found.WorkerID)
+	assert.Nil(t, found.LeaseExpiresAt)
+
+	// Completed jobs are never reset
+	found.Status = models.JobStatusCompleted
+	require.NoError(t, repo.UpdateJob(found))
+	assert.ErrorIs(t, repo.ResetJobForRedrive(job.ID.String()), ErrJobNotRedrivable)
+	assert.ErrorIs(t, repo.ResetJobForRedrive(uuid.New().String()), ErrJobNotFound)
+}
+
+func TestMemoryRepository_CancelJob(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	pending := &models.Job{Type: "cleanup"}
+	require.NoError(t, repo.CreateJob(pending))
+	cancelled, err := repo.CancelJob(pending.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCancelled, cancelled.Status)
+	_, err = repo.ClaimJob(pending.ID.String(), "worker-a", time.Minute)
+	assert.ErrorIs(t, err, ErrJobNotClaimable)
+
+	// A running job only gets a cancellation request
+	running := &models.Job{Type: "cleanup"}
+	require.NoError(t, repo.CreateJob(running))
+	_, err = repo.ClaimJob(running.ID.String(), "worker-a", time.Minute)
+	require.NoError(t, err)
+	requested, err := repo.CancelJob(running.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusProcessing, requested.Status)
+	require.NotNil(t, requested.CancelRequestedAt)
+
+	again, err := repo.CancelJob(running.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, *requested.CancelRequestedAt, *again.CancelRequestedAt, "repeat requests keep the first time")
+
+	_, err = repo.CancelJob(pending.ID.String())
+	assert.ErrorIs(t, err, ErrJobFinished)
+}
+
+func TestMemoryRepository_RetryJob(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	failed := &models.Job{Type: "cleanup", Data: models.JSONString("payload"), MaxAttempts: 5}
+	require.NoError(t, repo.CreateJob(failed))
+	_, err := repo.RetryJob(failed.ID.String(), "alice")
+	assert.ErrorIs(t, err, ErrJobNotRerunnable, "pending jobs can't be retried")
+
+	failed.Status = models.JobStatusFailed
+	failed.Error = "boom"
+	failed.Attempts = 5
+	require.NoError(t, repo.UpdateJob(failed))
+
+	rerun, err := repo.RetryJob(failed.ID.String(), "alice")
+	require.NoError(t, err)
+	assert.NotEqual(t, failed.ID, rerun.ID)
+	assert.Equal(t, models.JobStatusPending, rerun.Status)
+	assert.Equal(t, "cleanup", rerun.Type)
+	assert.Equal(t, models.JSONString("payload"), rerun.Data)
+	assert.Equal(t, 5, rerun.MaxAttempts)
+	assert.Zero(t, rerun.Attempts)
+	assert.Empty(t, rerun.Error)
+	require.NotNil(t, rerun.ParentID)
+	assert.Equal(t, failed.ID, *rerun.ParentID)
+	assert.Equal(t, "alice", rerun.RetriedBy)
+	assert.NotNil(t, rerun.RetriedAt)
+
+	// The original keeps its outcome and the re-run is queued through the outbox
+	original, err := repo.GetJob(failed.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, original.Status)
+	msgs, err := repo.ClaimOutboxMessages(10, time.Minute)
+	require.NoError(t, err)
+	var queued []uuid.UUID
+	for _, msg := range msgs {
+		queued = append(queued, msg.JobID)
+	}
+	assert.Contains(t, queued, rerun.ID)
+
+	_, err = repo.RetryJob(uuid.New().String(), "alice")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestMemoryRepository_Schedules(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	nightly := &models.Schedule{Name: "nightly", CronExpr: "@daily", JobType: "report", PayloadTemplate: "{}", Enabled: true}
+	hourly := &models.Schedule{Name: "hourly", CronExpr: "@hourly", JobType: "report", PayloadTemplate: "{}"}
+	require.NoError(t, repo.CreateSchedule(nightly))
+	require.NoError(t, repo.CreateSchedule(hourly))
+	assert.Equal(t, "UTC", nightly.Timezone)
+	assert.ErrorIs(t, repo.CreateSchedule(&models.Schedule{Name: "nightly"}), ErrDuplicateScheduleName)
+
+	schedules, err := repo.ListSchedules()
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	assert.Equal(t, "hourly", schedules[0].Name)
+
+	created := nightly.UpdatedAt
+	nightly.Enabled = false
+	require.NoError(t, repo.UpdateSchedule(nightly))
+	found, err := repo.GetSchedule(nightly.ID.String())
+	require.NoError(t, err)
+	assert.False(t, found.Enabled)
+	assert.False(t, found.UpdatedAt.Before(created))
+
+	hourly.Name = "nightly"
+	assert.ErrorIs(t, repo.UpdateSchedule(hourly), ErrDuplicateScheduleName)
+
+	require.NoError(t, repo.DeleteSchedule(nightly.ID.String()))
+	_, err = repo.GetSchedule(nightly.ID.String())
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+	assert.ErrorIs(t, repo.DeleteSchedule(nightly.ID.String()), ErrScheduleNotFound)
+	assert.ErrorIs(t, repo.UpdateSchedule(&models.Schedule{ID: uuid.New()}), ErrScheduleNotFound)
+}
+
+func TestMemoryRepository_Leases(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	_, err := repo.GetLease("scheduler")
+	assert.ErrorIs(t, err, ErrLeaseNotFound)
+
+	lease, held, err := repo.AcquireLease("scheduler", "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	acquired := lease.AcquiredAt
+
+	// Others can't take a live lease; renewing keeps when it was acquired
+	lease, held, err = repo.AcquireLease("scheduler", "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+	assert.Equal(t, "a", lease.Holder)
+	lease, held, err = repo.AcquireLease("scheduler", "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, acquired, lease.AcquiredAt)
+
+	// Only the holder can release it
+	require.NoError(t, repo.ReleaseLease("scheduler", "b"))
+	_, err = repo.GetLease("scheduler")
+	require.NoError(t, err)
+	require.NoError(t, repo.ReleaseLease("scheduler", "a"))
+	_, held, err = repo.AcquireLease("scheduler", "b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+}
+
+func TestMemoryRepository_ScheduleRuns(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	schedule := &models.Schedule{Name: "nightly", CronExpr: "@daily", JobType: "report", PayloadTemplate: "{}"}
+	require.NoError(t, repo.CreateSchedule(schedule))
+	assert.Equal(t, models.MisfireSkip, schedule.MisfirePolicy)
+
+	first := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	job := &models.Job{Type: "report", Data: models.JSONString("x")}
+	require.NoError(t, repo.RecordScheduleRun(&models.ScheduleRun{ScheduleID: schedule.ID, PlannedAt: first}, job))
+	require.NoError(t, repo.RecordScheduleRun(&models.ScheduleRun{ScheduleID: schedule.ID, PlannedAt: first.Add(24 * time.Hour)}, nil))
+
+	// The job is queued like any other
+	_, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, repo.outbox, 1)
+
+	// A planned time runs once; the rejected run creates no job
+	err = repo.RecordScheduleRun(&models.ScheduleRun{ScheduleID: schedule.ID, PlannedAt: first}, &models.Job{Type: "report"})
+	assert.ErrorIs(t, err, ErrDuplicateScheduleRun)
+	assert.Len(t, repo.jobs, 1)
+
+	runs, err := repo.ListScheduleRuns(schedule.ID.String(), 1)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.True(t, first.Add(24*time.Hour).Equal(runs[0].PlannedAt))
+	runs, err = repo.ListScheduleRuns(schedule.ID.String(), 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, job.ID, *runs[1].JobID)
+}
+
+func TestMemoryRepository_PromoteScheduledJobs(t *testing.T) {
+	repo := NewMemoryRepository()
+	now := time.Now()
+	soon, later := now.Add(10*time.Minute), now.Add(2*time.Hour)
+
+	due := &models.Job{Type: "cleanup", Status: models.JobStatusScheduled, RunAt: &soon}
+	notDue := &models.Job{Type: "cleanup", Status: models.JobStatusScheduled, RunAt: &later}
+	require.NoError(t, repo.CreateJob(due))
+	require.NoError(t, repo.CreateJob(notDue))
+
+	// Scheduled jobs stay out of the outbox until promoted
+	count, err := repo.CountUndeliveredOutbox()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	_, err = repo.ClaimJob(due.ID.String(), "worker-a", time.Minute)
+	assert.ErrorIs(t, err, ErrJobNotClaimable)
+
+	promoted, err := repo.PromoteScheduledJobs(now.Add(15*time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, promoted)
+
+	job, err := repo.GetJob(due.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, job.Status)
+	claimed, err := repo.ClaimOutboxMessages(10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, due.ID, claimed[0].JobID)
+	require.NotNil(t, claimed[0].RunAt)
+	assert.True(t, soon.Equal(*claimed[0].RunAt))
+
+	// Cancelling a scheduled job stops it from ever being promoted
+	cancelled, err := repo.CancelJob(notDue.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCancelled, cancelled.Status)
+	promoted, err = repo.PromoteScheduledJobs(later, 10)
+	require.NoError(t, err)
+	assert.Zero(t, promoted)
+}
+
+func TestMemoryRepository_Webhooks(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	silent := &models.Job{Type: "report"}
+	require.NoError(t, repo.CreateJob(silent))
+	silent.Status = models.JobStatusCompleted
+	require.NoError(t, repo.UpdateJob(silent))
+
+	job := &models.Job{Type: "report", CallbackURL: "https://example.com/hook"}
+	require.NoError(t, repo.CreateJob(job))
+	job.Status = models.JobStatusRetrying
+	require.NoError(t, repo.UpdateJob(job))
+	job.Status = models.JobStatusFailed
+	job.Error = "boom"
+	require.NoError(t, repo.UpdateJob(job))
+	// Saving the finished job again doesn't notify twice
+	require.NoError(t, repo.UpdateJob(job))
+
+	cancelled := &models.Job{Type: "report", CallbackURL: "https://example.com/hook"}
+	require.NoError(t, repo.CreateJob(cancelled))
+	_, err := repo.CancelJob(cancelled.ID.String())
+	require.NoError(t, err)
+
+	deliveries, err := repo.ListWebhookDeliveries(models.WebhookFilter{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+
+	failed, err := repo.ListWebhookDeliveries(models.WebhookFilter{JobID: &job.ID})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "job.failed", failed[0].Event)
+	assert.Equal(t, "https://example.com/hook", failed[0].URL)
+	assert.JSONEq(t, fmt.Sprintf(`{"event":"job.failed","job_id":%q,"type":"report","status":"failed","error":"boom","attempts":0,"finished_at":%q}`,
+		job.ID, failed[0].CreatedAt.Format(time.RFC3339Nano)), string(failed[0].Payload))
+
+	claimed, err := repo.ClaimWebhookDeliveries(10, time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+	claimed, err = repo.ClaimWebhookDeliveries(10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "claimed deliveries are leased")
+
+	replay, err := repo.ReplayWebhookDelivery(failed[0].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, &failed[0].ID, replay.ReplayOf)
+	assert.Equal(t, models.WebhookStatusPending, replay.Status)
+	assert.Equal(t, failed[0].Payload, replay.Payload)
+
+	_, err = repo.ReplayWebhookDelivery(uuid.NewString())
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	_, err = repo.ReplayWebhookDelivery("nope")
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimOutboxMessages leases up to limit undelivered messages that are due.
+// Claimed rows are hidden from other relays until the lease expires, so a
+// relay that dies mid-batch only delays delivery.
+func (r *OutboxRepository) ClaimOutboxMessages(limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+
+	now := time.Now()
+	err := r.db.Raw(`
+		UPDATE job_outbox
+		SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM job_outbox
+			WHERE delivered_at IS NULL AND next_attempt_at <= ?
+			ORDER BY created_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), now, now, limit,
+	).Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *OutboxRepository) MarkOutboxDelivered(id uuid.UUID) error {
+	now := time.Now()
+	return r.db.Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"delivered_at": now, "last_error": ""}).Error
+}
+
+func (r *OutboxRepository) MarkOutboxFailed(id uuid.UUID, reason string, nextAttemptAt time.Time) error {
+	return r.db.Model(&models.OutboxMessage{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_error": reason, "next_attempt_at": nextAttemptAt}).Error
+}
+
+func (r *OutboxRepository) CountUndeliveredOutbox() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.OutboxMessage{}).Where("delivered_at IS NULL").Count(&count).Error
+	return count, err
+}
+
+// PurgeDeliveredOutbox removes delivered messages older than the cutoff.
+func (r *OutboxRepository) PurgeDeliveredOutbox(before time.Time) (int64, error) {
+	result := r.db.Where("delivered_at IS NOT NULL AND delivered_at < ?", before).Delete(&models.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
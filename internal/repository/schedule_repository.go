@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+var (
+	ErrScheduleNotFound      = errors.New("schedule not found")
+	ErrDuplicateScheduleName = errors.New("schedule name already taken")
+	ErrDuplicateScheduleRun  = errors.New("schedule already ran for this time")
+)
+
+type ScheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+func (r *ScheduleRepository) CreateSchedule(schedule *models.Schedule) error {
+	if schedule == nil {
+		return errors.New("schedule cannot be nil")
+	}
+	if err := r.db.Create(schedule).Error; err != nil {
+		return scheduleError(err)
+	}
+	return nil
+}
+
+func (r *ScheduleRepository) GetSchedule(id string) (*models.Schedule, error) {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var schedule models.Schedule
+	if err := r.db.First(&schedule, "id = ?", scheduleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *ScheduleRepository) ListSchedules() ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	if err := r.db.Order("name").Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+func (r *ScheduleRepository) UpdateSchedule(schedule *models.Schedule) error {
+	if schedule == nil {
+		return errors.New("schedule cannot be nil")
+	}
+	result := r.db.Model(schedule).Select("*").Omit("id", "created_at").Updates(schedule)
+	if result.Error != nil {
+		return scheduleError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+func (r *ScheduleRepository) DeleteSchedule(id string) error {
+	scheduleID, err := uuid.Parse(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	result := r.db.Delete(&models.Schedule{}, "id = ?", scheduleID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete schedule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// RecordScheduleRun records a firing of a schedule together with the job it
+// created, if any, which is queued through the outbox like any other. It
+// returns ErrDuplicateScheduleRun, creating nothing, if the schedule already
+// ran for run.PlannedAt.
+func (r *ScheduleRepository) RecordScheduleRun(run *models.ScheduleRun, job *models.Job) error {
+	if run == nil {
+		return errors.New("schedule run cannot be nil")
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if job != nil {
+			if job.ID == uuid.Nil {
+				job.ID = uuid.New()
+			}
+			run.JobID = &job.ID
+		}
+		if err := tx.Create(run).Error; err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicateScheduleRun
+			}
+			return fmt.Errorf("failed to record schedule run: %w", err)
+		}
+		if job == nil {
+			return nil
+		}
+		return createJob(tx, job)
+	})
+}
+
+// ListScheduleRuns returns a schedule's most recent runs by planned time
+func (r *ScheduleRepository) ListScheduleRuns(scheduleID string, limit int) ([]models.ScheduleRun, error) {
+	id, err := uuid.Parse(scheduleID)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var runs []models.ScheduleRun
+	err = r.db.Where("schedule_id = ?", id).Order("planned_at DESC").Limit(limit).Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedule runs: %w", err)
+	}
+	return runs, nil
+}
+
+// scheduleError maps a unique violation on the name to ErrDuplicateScheduleName
+func scheduleError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrDuplicateScheduleName
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+var ErrWebhookNotFound = errors.New("webhook delivery not found")
+
+// WebhookRepository stores the deliveries written when jobs with a callback
+// URL finish
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// ClaimWebhookDeliveries leases up to limit pending deliveries that are due,
+// counting the attempt. Like outbox messages, claimed rows are hidden from
+// other dispatchers until the lease expires.
+func (r *WebhookRepository) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	now := time.Now()
+	err := r.db.Raw(`
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), now, models.WebhookStatusPending, now, limit,
+	).Scan(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery saves the outcome of an attempt
+func (r *WebhookRepository) UpdateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+// ListWebhookDeliveries returns the deliveries matching filter, newest first
+func (r *WebhookRepository) ListWebhookDeliveries(filter models.WebhookFilter) ([]models.WebhookDelivery, error) {
+	query := r.db.Order("created_at DESC").Limit(filter.Limit)
+	if filter.JobID != nil {
+		query = query.Where("job_id = ?", *filter.JobID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ReplayWebhookDelivery queues the delivery's notification to be sent again
+// as a new delivery, leaving the original's log untouched
+func (r *WebhookRepository) ReplayWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	deliveryID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var delivery models.WebhookDelivery
+	if err := r.db.First(&delivery, "id = ?", deliveryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	replay := delivery.Replay(time.Now())
+	if err := r.db.Create(replay).Error; err != nil {
+		return nil, fmt.Errorf("failed to replay webhook delivery: %w", err)
+	}
+	return replay, nil
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+)
+
+const promoteBatchSize = 100
+
+// Promoter queues jobs held as scheduled once their run_at is within
+// queue.MaxDelay, the longest delivery delay SQS supports, so the queue
+// delivers them on time. Promotion skips rows locked by other promoters, so
+// every worker instance can run one.
+type Promoter struct {
+	jobs     interfaces.ScheduledJobs
+	logger   *slog.Logger
+	interval time.Duration
+}
+
+func NewPromoter(jobs interfaces.ScheduledJobs, logger *slog.Logger, interval time.Duration) *Promoter {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Promoter{jobs: jobs, logger: logger, interval: interval}
+}
+
+func (p *Promoter) Start(ctx context.Context) error {
+	p.logger.Info("Scheduled job promoter started", "interval", p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.Promote()
+
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Scheduled job promoter shutting down")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Promote queues every scheduled job due within queue.MaxDelay and returns
+// how many it queued
+func (p *Promoter) Promote() int {
+	total := 0
+	for {
+		n, err := p.jobs.PromoteScheduledJobs(time.Now().Add(queue.MaxDelay), promoteBatchSize)
+		if err != nil {
+			p.logger.Error("failed to promote scheduled jobs", "error", err)
+			return total
+		}
+		total += n
+		if n < promoteBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		p.logger.Info("promoted scheduled jobs", "count", total)
+	}
+	return total
+}
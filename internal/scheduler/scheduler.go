@@ -1,157 +1,427 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
+	// Schedules name IANA timezones, which slim images may not ship
+	_ "time/tzdata"
 
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
-	
+
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+const (
+	defaultSyncInterval = 15 * time.Second
+	defaultLeaseTTL     = 30 * time.Second
+
+	// maxCatchUpRuns caps how many missed firings of one schedule are made
+	// up for under MisfireRunAll; the latest ones are kept
+	maxCatchUpRuns = 100
+)
+
+// LeaseName is the lease scheduler instances compete for; its holder is the
+// one that runs the schedules
+const LeaseName = "scheduler"
+
+var (
+	ErrInvalidCron     = errors.New("invalid cron expression")
+	ErrInvalidTimezone = errors.New("invalid timezone")
+	ErrInvalidTemplate = errors.New("invalid payload template")
 )
 
+// parser accepts five fields, an optional leading seconds field, or a
+// descriptor such as @hourly
+var parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// TemplateData is what a schedule's payload template is executed with
+type TemplateData struct {
+	// ScheduledAt is when the schedule fired, in the schedule's timezone
+	ScheduledAt time.Time
+	Schedule    string
+}
+
+// Parse parses a schedule's cron expression in its timezone, UTC if empty
+func Parse(expr, timezone string) (cron.Schedule, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, timezone)
+	}
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		return nil, fmt.Errorf("%w: use the timezone field instead of a TZ prefix", ErrInvalidCron)
+	}
+
+	schedule, err := parser.Parse("CRON_TZ=" + timezone + " " + expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCron, err)
+	}
+	return schedule, nil
+}
+
+// RenderPayload executes a payload template, which must produce JSON
+func RenderPayload(tmpl string, data TemplateData) (models.JSON, error) {
+	t, err := template.New("payload").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("%w: output is not valid JSON", ErrInvalidTemplate)
+	}
+	return models.JSON(buf.Bytes()), nil
+}
+
+type Options struct {
+	// SyncInterval is how often schedules are reloaded so that changes made
+	// through the API apply without a restart
+	SyncInterval time.Duration
+
+	// Leases, if set, elects a single instance to run the schedules; the
+	// others stand by and take over once the leader's lease lapses. Without
+	// it every instance runs them.
+	Leases interfaces.LeaseRepository
+	// InstanceID names this instance as lease holder; it defaults to the
+	// hostname and process ID
+	InstanceID string
+	// LeaseTTL is how long leadership survives without renewal. The leader
+	// renews every third of it.
+	LeaseTTL time.Duration
+}
+
+// entry is a schedule as it was when added to the cron runner
+type entry struct {
+	id        cron.EntryID
+	updatedAt time.Time
+}
+
 type Scheduler struct {
-	cron   *cron.Cron
-	repo   interfaces.Repository
-	queue  interfaces.Queue
-	logger *slog.Logger
+	cron      *cron.Cron
+	schedules interfaces.ScheduleRepository
+	logger    *slog.Logger
+	opts      Options
+
+	// leading is only touched by the Start goroutine
+	leading bool
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]entry
 }
 
-func New(repo interfaces.Repository, queue interfaces.Queue, logger *slog.Logger) *Scheduler {
+// New creates a scheduler running the schedules stored in schedules. Jobs are
+// queued through the outbox written with each run, so the scheduler never
+// talks to the queue directly.
+func New(schedules interfaces.ScheduleRepository, logger *slog.Logger, opts Options) *Scheduler {
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = defaultLeaseTTL
+	}
+	if opts.InstanceID == "" {
+		opts.InstanceID = defaultInstanceID()
+	}
 	return &Scheduler{
-		cron:   cron.New(cron.WithSeconds()),
-		repo:   repo,
-		queue:  queue,
-		logger: logger,
+		cron:      cron.New(),
+		schedules: schedules,
+		logger:    logger,
+		opts:      opts,
+		entries:   make(map[uuid.UUID]entry),
 	}
 }
 
+// Start runs the schedules while this instance is the leader, until ctx is
+// done. Leadership is released on the way out so a standby takes over on
+// its next renewal instead of waiting for the lease to expire.
 func (s *Scheduler) Start(ctx context.Context) error {
-	s.logger.Info("Starting scheduler")
+	s.logger.Info("Starting scheduler", "sync_interval", s.opts.SyncInterval, "instance_id", s.opts.InstanceID)
 
-	// Every 5 minutes: Clean up old completed jobs
-	_, err := s.cron.AddFunc("0 */5 * * * *", func() {
-		s.cleanupOldJobs(ctx)
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add cleanup job: %w", err)
+	s.elect()
+
+	syncTicker := time.NewTicker(s.opts.SyncInterval)
+	defer syncTicker.Stop()
+	var renew <-chan time.Time
+	if s.opts.Leases != nil {
+		leaseTicker := time.NewTicker(s.opts.LeaseTTL / 3)
+		defer leaseTicker.Stop()
+		renew = leaseTicker.C
 	}
 
-	// Every hour: Generate system health report
-	_, err = s.cron.AddFunc("0 0 * * * *", func() {
-		s.generateHealthReport(ctx)
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add health report job: %w", err)
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Shutting down scheduler")
+			if s.leading {
+				s.stepDown()
+				if s.opts.Leases != nil {
+					if err := s.opts.Leases.ReleaseLease(LeaseName, s.opts.InstanceID); err != nil {
+						s.logger.Error("failed to release scheduler lease", "error", err)
+					}
+				}
+			}
+			return nil
+		case <-renew:
+			s.elect()
+		case <-syncTicker.C:
+			if !s.leading {
+				continue
+			}
+			if err := s.Sync(); err != nil {
+				s.logger.Error("failed to sync schedules", "error", err)
+			}
+		}
 	}
+}
 
-	// Daily at 2 AM: Data aggregation
-	_, err = s.cron.AddFunc("0 0 2 * * *", func() {
-		s.performDataAggregation(ctx)
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add aggregation job: %w", err)
+// elect acquires or renews the scheduler lease and starts or stops the cron
+// runner to match. Failing to renew counts as losing the lease, since
+// another instance may take it over once it lapses.
+func (s *Scheduler) elect() {
+	leader := true
+	if s.opts.Leases != nil {
+		lease, held, err := s.opts.Leases.AcquireLease(LeaseName, s.opts.InstanceID, s.opts.LeaseTTL)
+		if err != nil {
+			s.logger.Error("failed to acquire scheduler lease", "error", err)
+		} else if !held {
+			s.logger.Debug("standing by for scheduler leader", "leader", lease.Holder, "expires_at", lease.ExpiresAt)
+		}
+		leader = err == nil && held
 	}
 
-	// Every 30 seconds: Process batch imports (if any)
-	_, err = s.cron.AddFunc("*/30 * * * * *", func() {
-		s.processBatchImports(ctx)
-	})
-	if err != nil {
-		return fmt.Errorf("failed to add batch import job: %w", err)
+	switch {
+	case leader && !s.leading:
+		s.logger.Info("elected scheduler leader", "instance_id", s.opts.InstanceID)
+		s.lead()
+	case !leader && s.leading:
+		s.logger.Warn("lost scheduler leadership", "instance_id", s.opts.InstanceID)
+		s.stepDown()
 	}
+}
 
+// lead starts running the schedules, then makes up for the firings missed
+// while no leader was running them. Those are all due no later than the time
+// taken before starting the runner, so none is fired twice.
+func (s *Scheduler) lead() {
+	if err := s.Sync(); err != nil {
+		s.logger.Error("failed to sync schedules", "error", err)
+	}
+	now := time.Now()
 	s.cron.Start()
-	
-	// Wait for context cancellation
-	<-ctx.Done()
-	s.logger.Info("Shutting down scheduler")
-	
+	s.leading = true
+
+	if err := s.catchUp(now); err != nil {
+		s.logger.Error("failed to catch up missed schedule runs", "error", err)
+	}
+}
+
+// stepDown stops the cron runner, waiting for jobs being created. Entries
+// stay registered and are reconciled by the next Sync.
+func (s *Scheduler) stepDown() {
 	ctxStop := s.cron.Stop()
 	<-ctxStop.Done()
-	
-	return nil
+	s.leading = false
 }
 
-func (s *Scheduler) cleanupOldJobs(ctx context.Context) {
-	s.logger.Info("Running cleanup job")
-	
-	job := &models.Job{
-		ID:     uuid.New(),
-		Type:   "cleanup",
-		Data:   "Remove completed jobs older than 7 days",
-		Status: models.JobStatusPending,
+// Sync reconciles the cron entries with the stored schedules: new and
+// re-enabled schedules are added, edited ones replaced, and deleted or
+// paused ones removed.
+func (s *Scheduler) Sync() error {
+	schedules, err := s.schedules.ListSchedules()
+	if err != nil {
+		return fmt.Errorf("failed to load schedules: %w", err)
 	}
-	
-	if err := s.repo.CreateJob(job); err != nil {
-		s.logger.Error("failed to create cleanup job", "error", err)
-		return
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	enabled := make(map[uuid.UUID]bool, len(schedules))
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		enabled[schedule.ID] = true
+
+		current, ok := s.entries[schedule.ID]
+		if ok && current.updatedAt.Equal(schedule.UpdatedAt) {
+			continue
+		}
+		if ok {
+			s.cron.Remove(current.id)
+		}
+
+		// An invalid schedule is remembered without an entry so it is
+		// only reported again once edited
+		next := entry{updatedAt: schedule.UpdatedAt}
+		cronSchedule, err := Parse(schedule.CronExpr, schedule.Timezone)
+		if err != nil {
+			s.logger.Error("skipping invalid schedule", "schedule", schedule.Name, "error", err)
+		} else {
+			next.id = s.cron.Schedule(cronSchedule, cron.FuncJob(func() {
+				s.fire(schedule, s.plannedAt(schedule.ID), false)
+			}))
+			s.logger.Info("schedule loaded", "schedule", schedule.Name, "cron", schedule.CronExpr, "timezone", schedule.Timezone)
+		}
+		s.entries[schedule.ID] = next
+	}
+
+	for id, current := range s.entries {
+		if !enabled[id] {
+			s.cron.Remove(current.id)
+			delete(s.entries, id)
+			s.logger.Info("schedule unloaded", "schedule_id", id)
+		}
 	}
-	
-	if err := s.queue.SendMessage(ctx, job.ID.String()); err != nil {
-		s.logger.Error("failed to queue cleanup job", "error", err)
+	return nil
+}
+
+// plannedAt returns when the cron entry of the schedule with id was due. The
+// runner records that just before starting the entry's job.
+func (s *Scheduler) plannedAt(id uuid.UUID) time.Time {
+	s.mu.Lock()
+	current := s.entries[id]
+	s.mu.Unlock()
+
+	if prev := s.cron.Entry(current.id).Prev; !prev.IsZero() {
+		return prev
 	}
+	return time.Now().Truncate(time.Second)
 }
 
-func (s *Scheduler) generateHealthReport(ctx context.Context) {
-	s.logger.Info("Running health report job")
-	
-	job := &models.Job{
-		ID:     uuid.New(),
-		Type:   "health-report",
-		Data:   fmt.Sprintf("Generate system health report at %s", time.Now().Format(time.RFC3339)),
-		Status: models.JobStatusPending,
+// fire creates the job for the firing of schedule planned at planned and
+// records the run. A template that fails to render is recorded as a run
+// without a job.
+func (s *Scheduler) fire(schedule models.Schedule, planned time.Time, catchUp bool) {
+	if loc, err := time.LoadLocation(schedule.Timezone); err == nil {
+		planned = planned.In(loc)
 	}
-	
-	if err := s.repo.CreateJob(job); err != nil {
-		s.logger.Error("failed to create health report job", "error", err)
+	run := &models.ScheduleRun{
+		ScheduleID: schedule.ID,
+		PlannedAt:  planned,
+		FiredAt:    time.Now(),
+		CatchUp:    catchUp,
+	}
+
+	var job *models.Job
+	data, err := RenderPayload(schedule.PayloadTemplate, TemplateData{ScheduledAt: planned, Schedule: schedule.Name})
+	if err != nil {
+		s.logger.Error("failed to render schedule payload", "schedule", schedule.Name, "error", err)
+		run.Error = err.Error()
+	} else {
+		job = &models.Job{
+			ID:       uuid.New(),
+			Type:     schedule.JobType,
+			Data:     data,
+			Status:   models.JobStatusPending,
+			Priority: schedule.Priority,
+		}
+	}
+
+	if err := s.schedules.RecordScheduleRun(run, job); err != nil {
+		if errors.Is(err, repository.ErrDuplicateScheduleRun) {
+			s.logger.Info("schedule already ran", "schedule", schedule.Name, "planned_at", planned)
+			return
+		}
+		s.logger.Error("failed to create scheduled job", "schedule", schedule.Name, "planned_at", planned, "error", err)
 		return
 	}
-	
-	if err := s.queue.SendMessage(ctx, job.ID.String()); err != nil {
-		s.logger.Error("failed to queue health report job", "error", err)
+	if job != nil {
+		metrics.JobsCreated.WithLabelValues(job.Type).Inc()
+		s.logger.Info("scheduled job created", "schedule", schedule.Name, "planned_at", planned,
+			"catch_up", catchUp, "job_id", job.ID, "job_type", job.Type)
 	}
 }
 
-func (s *Scheduler) performDataAggregation(ctx context.Context) {
-	s.logger.Info("Running data aggregation job")
-	
-	job := &models.Job{
-		ID:     uuid.New(),
-		Type:   "data-aggregation",
-		Data:   "Aggregate daily metrics and statistics",
-		Status: models.JobStatusPending,
+// catchUp applies each enabled schedule's misfire policy to the firings it
+// missed up to now
+func (s *Scheduler) catchUp(now time.Time) error {
+	schedules, err := s.schedules.ListSchedules()
+	if err != nil {
+		return fmt.Errorf("failed to load schedules: %w", err)
 	}
-	
-	if err := s.repo.CreateJob(job); err != nil {
-		s.logger.Error("failed to create aggregation job", "error", err)
-		return
+
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+		if err := s.catchUpSchedule(schedule, now); err != nil {
+			s.logger.Error("failed to find missed runs", "schedule", schedule.Name, "error", err)
+		}
 	}
-	
-	if err := s.queue.SendMessage(ctx, job.ID.String()); err != nil {
-		s.logger.Error("failed to queue aggregation job", "error", err)
+	return nil
+}
+
+func (s *Scheduler) catchUpSchedule(schedule models.Schedule, now time.Time) error {
+	missed, err := s.missed(schedule, now)
+	if err != nil || len(missed) == 0 {
+		return err
+	}
+
+	s.logger.Warn("schedule missed runs", "schedule", schedule.Name, "missed", len(missed),
+		"since", missed[0], "misfire_policy", schedule.MisfirePolicy)
+	switch schedule.MisfirePolicy {
+	case models.MisfireRunOnce:
+		s.fire(schedule, missed[len(missed)-1], true)
+	case models.MisfireRunAll:
+		for _, planned := range missed {
+			s.fire(schedule, planned, true)
+		}
 	}
+	return nil
 }
 
-func (s *Scheduler) processBatchImports(ctx context.Context) {
-	// Check if there are any pending batch import requests
-	jobs, err := s.repo.ListJobs("pending", 10)
+// missed lists, oldest first, the times up to now that schedule should have
+// fired but didn't. It looks back no further than the schedule's catch-up
+// window, its latest run or its last change, whichever is latest, and keeps
+// at most maxCatchUpRuns of them.
+func (s *Scheduler) missed(schedule models.Schedule, now time.Time) ([]time.Time, error) {
+	cronSchedule, err := Parse(schedule.CronExpr, schedule.Timezone)
 	if err != nil {
-		s.logger.Error("failed to list pending jobs", "error", err)
-		return
+		return nil, err
+	}
+
+	from := now.Add(-schedule.CatchUpWindow())
+	if schedule.UpdatedAt.After(from) {
+		from = schedule.UpdatedAt
+	}
+	runs, err := s.schedules.ListScheduleRuns(schedule.ID.String(), 1)
+	if err != nil {
+		return nil, err
 	}
-	
-	batchCount := 0
-	for _, job := range jobs {
-		if job.Type == "batch-import" {
-			batchCount++
+	if len(runs) > 0 && runs[0].PlannedAt.After(from) {
+		from = runs[0].PlannedAt
+	}
+
+	var missed []time.Time
+	for t := cronSchedule.Next(from); !t.IsZero() && !t.After(now); t = cronSchedule.Next(t) {
+		missed = append(missed, t)
+		if len(missed) > maxCatchUpRuns {
+			missed = missed[1:]
 		}
 	}
-	
-	if batchCount > 0 {
-		s.logger.Info("Found batch import jobs to process", "count", batchCount)
+	return missed, nil
+}
+
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "scheduler"
 	}
-}
\ No newline at end of file
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
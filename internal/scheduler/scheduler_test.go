@@ -0,0 +1,283 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+func TestParse(t *testing.T) {
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		timezone string
+		next     time.Time
+		wantErr  error
+	}{
+		{"0 */5 * * * *", "", time.Date(2026, 3, 1, 12, 5, 0, 0, time.UTC), nil},
+		{"30 9 * * *", "UTC", time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC), nil},
+		{"0 9 * * *", "America/New_York", time.Date(2026, 3, 1, 14, 0, 0, 0, time.UTC), nil},
+		{"@hourly", "", time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC), nil},
+		{"61 * * * *", "", time.Time{}, ErrInvalidCron},
+		{"TZ=UTC 0 9 * * *", "", time.Time{}, ErrInvalidCron},
+		{"0 9 * * *", "Nowhere/Special", time.Time{}, ErrInvalidTimezone},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr, tt.timezone)
+		if tt.wantErr != nil {
+			assert.ErrorIs(t, err, tt.wantErr, tt.expr)
+			continue
+		}
+		require.NoError(t, err, tt.expr)
+		assert.True(t, tt.next.Equal(schedule.Next(from)), "%s in %q: got %s", tt.expr, tt.timezone, schedule.Next(from))
+	}
+}
+
+func TestRenderPayload(t *testing.T) {
+	data := TemplateData{
+		ScheduledAt: time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC),
+		Schedule:    "nightly",
+	}
+
+	out, err := RenderPayload(`{"schedule": "{{.Schedule}}", "day": "{{.ScheduledAt.Format "2006-01-02"}}"}`, data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"schedule": "nightly", "day": "2026-03-01"}`, out.String())
+
+	_, err = RenderPayload(`{{.Missing}}`, data)
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	_, err = RenderPayload(`{{`, data)
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+	_, err = RenderPayload(`not json`, data)
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+}
+
+func TestScheduler_Sync(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	s := New(repo, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{})
+
+	nightly := &models.Schedule{Name: "nightly", CronExpr: "0 2 * * *", JobType: "report", PayloadTemplate: `"x"`, Enabled: true}
+	broken := &models.Schedule{Name: "broken", CronExpr: "not cron", JobType: "report", PayloadTemplate: `"x"`, Enabled: true}
+	paused := &models.Schedule{Name: "paused", CronExpr: "@hourly", JobType: "report", PayloadTemplate: `"x"`}
+	for _, schedule := range []*models.Schedule{nightly, broken, paused} {
+		require.NoError(t, repo.CreateSchedule(schedule))
+	}
+
+	require.NoError(t, s.Sync())
+	assert.Len(t, s.cron.Entries(), 1)
+	first := s.entries[nightly.ID].id
+	assert.NotZero(t, first)
+
+	// Unchanged schedules keep their entries
+	require.NoError(t, s.Sync())
+	assert.Equal(t, first, s.entries[nightly.ID].id)
+
+	// Edits replace the entry and resuming adds one
+	nightly.CronExpr = "0 3 * * *"
+	require.NoError(t, repo.UpdateSchedule(nightly))
+	paused.Enabled = true
+	require.NoError(t, repo.UpdateSchedule(paused))
+	require.NoError(t, s.Sync())
+	assert.Len(t, s.cron.Entries(), 2)
+	assert.NotEqual(t, first, s.entries[nightly.ID].id)
+
+	// Deleting and pausing remove them
+	require.NoError(t, repo.DeleteSchedule(nightly.ID.String()))
+	paused.Enabled = false
+	require.NoError(t, repo.UpdateSchedule(paused))
+	require.NoError(t, s.Sync())
+	assert.Empty(t, s.cron.Entries())
+}
+
+func TestScheduler_Fire(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	s := New(repo, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{})
+
+	schedule := &models.Schedule{Name: "nightly", CronExpr: "@daily", JobType: "report", PayloadTemplate: `{"schedule": "{{.Schedule}}", "at": "{{.ScheduledAt.Format "15:04"}}"}`, Timezone: "Europe/Madrid", Priority: models.JobPriorityLow}
+	require.NoError(t, repo.CreateSchedule(schedule))
+	planned := time.Date(2026, 3, 1, 1, 0, 0, 0, time.UTC)
+
+	s.fire(*schedule, planned, false)
+	// Firing again for the same time is a no-op
+	s.fire(*schedule, planned, false)
+
+	jobs, err := repo.ListJobs("pending", 10)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "report", jobs[0].Type)
+	assert.Equal(t, models.JobPriorityLow, jobs[0].Priority)
+	assert.JSONEq(t, `{"schedule": "nightly", "at": "02:00"}`, jobs[0].Data.String())
+
+	runs, err := repo.ListScheduleRuns(schedule.ID.String(), 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.True(t, planned.Equal(runs[0].PlannedAt))
+	assert.Equal(t, jobs[0].ID, *runs[0].JobID)
+	assert.False(t, runs[0].CatchUp)
+
+	// A broken template is recorded as a run without a job
+	schedule.PayloadTemplate = `{{.Nope}}`
+	s.fire(*schedule, planned.Add(24*time.Hour), false)
+	runs, err = repo.ListScheduleRuns(schedule.ID.String(), 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Nil(t, runs[0].JobID)
+	assert.Contains(t, runs[0].Error, "invalid payload template")
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	now := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+	lastRun := time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	tests := []struct {
+		name        string
+		policy      models.MisfirePolicy
+		window      time.Duration
+		wantPlanned []time.Time
+	}{
+		{"skip", models.MisfireSkip, week, nil},
+		{"run once", models.MisfireRunOnce, week, []time.Time{time.Date(2026, 3, 5, 2, 0, 0, 0, time.UTC)}},
+		{"run all", models.MisfireRunAll, week, []time.Time{
+			time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 4, 2, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 5, 2, 0, 0, 0, time.UTC),
+		}},
+		// The window leaves out the firing on the 3rd
+		{"run all within window", models.MisfireRunAll, 48 * time.Hour, []time.Time{
+			time.Date(2026, 3, 4, 2, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 5, 2, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			s := New(repo, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{})
+
+			schedule := &models.Schedule{
+				Name: "aggregation", CronExpr: "0 2 * * *", JobType: "report", PayloadTemplate: `"x"`,
+				Enabled: true, MisfirePolicy: tt.policy, CatchUpWindowSeconds: int(tt.window / time.Second),
+			}
+			require.NoError(t, repo.CreateSchedule(schedule))
+			require.NoError(t, repo.RecordScheduleRun(&models.ScheduleRun{ScheduleID: schedule.ID, PlannedAt: lastRun}, nil))
+			// As if it was last changed long before it last ran
+			schedule.UpdatedAt = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			require.NoError(t, s.catchUpSchedule(*schedule, now))
+
+			runs, err := repo.ListScheduleRuns(schedule.ID.String(), 10)
+			require.NoError(t, err)
+			var planned []time.Time
+			for _, run := range runs[:len(runs)-1] {
+				assert.True(t, run.CatchUp)
+				planned = append([]time.Time{run.PlannedAt.UTC()}, planned...)
+			}
+			assert.Equal(t, tt.wantPlanned, planned)
+		})
+	}
+}
+
+func TestScheduler_Missed(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	s := New(repo, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{})
+	now := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+
+	// Edits reset the baseline
+	schedule := models.Schedule{ID: uuid.New(), CronExpr: "0 * * * *", UpdatedAt: now.Add(-90 * time.Minute)}
+	missed, err := s.missed(schedule, now)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{now.Add(-time.Hour), now}, missed)
+
+	// At most maxCatchUpRuns, the latest
+	schedule = models.Schedule{ID: uuid.New(), CronExpr: "* * * * * *", UpdatedAt: now.Add(-time.Hour)}
+	missed, err = s.missed(schedule, now)
+	require.NoError(t, err)
+	require.Len(t, missed, maxCatchUpRuns)
+	assert.Equal(t, now, missed[len(missed)-1])
+}
+
+func TestScheduler_Election(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := New(repo, logger, Options{Leases: repo, InstanceID: "a", LeaseTTL: 50 * time.Millisecond})
+	b := New(repo, logger, Options{Leases: repo, InstanceID: "b", LeaseTTL: 50 * time.Millisecond})
+
+	a.elect()
+	b.elect()
+	assert.True(t, a.leading)
+	assert.False(t, b.leading)
+
+	// a stops renewing, as if it died, and b takes over once the lease lapses
+	time.Sleep(60 * time.Millisecond)
+	b.elect()
+	assert.True(t, b.leading)
+	lease, err := repo.GetLease(LeaseName)
+	require.NoError(t, err)
+	assert.Equal(t, "b", lease.Holder)
+
+	// a finds out on its next renewal
+	a.elect()
+	assert.False(t, a.leading)
+	b.stepDown()
+}
+
+func TestScheduler_StartReleasesLease(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts := func(id string) Options {
+		return Options{Leases: repo, InstanceID: id, LeaseTTL: time.Minute, SyncInterval: time.Minute}
+	}
+	holder := func() string {
+		lease, err := repo.GetLease(LeaseName)
+		if err != nil {
+			return ""
+		}
+		return lease.Holder
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(repo, logger, opts("a")).Start(ctx)
+	}()
+	require.Eventually(t, func() bool { return holder() == "a" }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+	assert.Empty(t, holder(), "shutting down hands the lease back")
+
+	b := New(repo, logger, opts("b"))
+	b.elect()
+	assert.True(t, b.leading)
+	b.stepDown()
+}
+
+func TestPromoter_Promote(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	p := NewPromoter(repo, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Minute)
+
+	soon, later := time.Now().Add(time.Minute), time.Now().Add(time.Hour)
+	for i := 0; i < promoteBatchSize+5; i++ {
+		require.NoError(t, repo.CreateJob(&models.Job{Type: "report", Status: models.JobStatusScheduled, RunAt: &soon}))
+	}
+	require.NoError(t, repo.CreateJob(&models.Job{Type: "report", Status: models.JobStatusScheduled, RunAt: &later}))
+
+	// Batches are drained until only jobs beyond the delay SQS supports remain
+	assert.Equal(t, promoteBatchSize+5, p.Promote())
+	assert.Zero(t, p.Promote())
+
+	scheduled, err := repo.ListJobs(string(models.JobStatusScheduled), 10)
+	require.NoError(t, err)
+	assert.Len(t, scheduled, 1)
+}
@@ -0,0 +1,96 @@
+// Package tracing sets up OpenTelemetry tracing and carries trace context
+// across the outbox and the queue, so a single trace follows a job from
+// submission to completion.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
+)
+
+// tracerName is the instrumentation scope of the spans this service starts
+const tracerName = "github.com/amayabdaniel/dab-aws-go-service-worker"
+
+// propagator reads and writes W3C trace context and baggage. It is used
+// directly rather than through the global, so context is carried even
+// when tracing is disabled in this process.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Setup installs the global tracer provider for service, exporting spans
+// as configured. With tracing disabled spans are not recorded, but trace
+// context received from callers is still passed on. The returned function
+// flushes and stops the exporter.
+func Setup(ctx context.Context, cfg *config.Config, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+	if !cfg.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, error) {
+	switch cfg.TracingExporter {
+	case "", "otlp":
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.TracingEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.TracingExporter)
+	}
+}
+
+// Tracer returns the tracer for the service's own spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Inject returns the trace context of ctx as string pairs, or nil if ctx
+// carries none
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx with the trace context read from carrier, as written
+// by Inject
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier(carrier))
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing/tracingtest"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/pkg/config"
+)
+
+func TestInjectExtract(t *testing.T) {
+	tracingtest.Record(t)
+
+	assert.Nil(t, Inject(context.Background()), "nothing to carry without a span")
+	assert.Equal(t, context.Background(), Extract(context.Background(), nil))
+
+	ctx, span := Tracer().Start(context.Background(), "submit")
+	defer span.End()
+
+	carrier := Inject(ctx)
+	require.Contains(t, carrier, "traceparent")
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	assert.True(t, extracted.IsRemote())
+	assert.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{"disabled", config.Config{TracingExporter: "bogus"}, false},
+		{"otlp", config.Config{TracingEnabled: true, TracingEndpoint: "http://localhost:4318"}, false},
+		{"stdout", config.Config{TracingEnabled: true, TracingExporter: "stdout"}, false},
+		{"unknown exporter", config.Config{TracingEnabled: true, TracingExporter: "bogus"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup replaces the global provider; Record puts it back
+			tracingtest.Record(t)
+
+			shutdown, err := Setup(context.Background(), &tt.cfg, "test")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Package tracingtest records spans in memory for tests
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Record installs a global tracer provider that keeps finished spans in the
+// returned exporter, restoring the previous provider when t ends. Tests
+// using it must not run in parallel.
+func Record(t testing.TB) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+// Find returns the finished span named name, or nil
+func Find(exporter *tracetest.InMemoryExporter, name string) *tracetest.SpanStub {
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			return &span
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublic(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00:ec2::254":    false,
+		"0.0.0.0":          false,
+		"::":               false,
+		"100.64.0.1":       false,
+		"224.0.0.1":        false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, isPublic(netip.MustParseAddr(addr)), addr)
+	}
+}
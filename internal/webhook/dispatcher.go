@@ -0,0 +1,223 @@
+// Package webhook sends the signed notifications of finished jobs to their
+// callback URLs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+)
+
+const (
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+
+	// maxErrorBody is how much of a failed response is kept in the log
+	maxErrorBody = 512
+)
+
+// ErrNoSecret is returned by NewDispatcher without a secret to sign with;
+// notifications are never sent unsigned
+var ErrNoSecret = errors.New("a webhook secret is required to sign notifications")
+
+// Options configures a Dispatcher. Zero values get defaults, except Secret,
+// which is required.
+type Options struct {
+	// Secret signs every notification
+	Secret string
+	// MaxAttempts is how many times a delivery is tried before it fails
+	MaxAttempts int
+	// Timeout bounds each request
+	Timeout   time.Duration
+	Interval  time.Duration
+	BatchSize int
+	// Client sends the notifications. The default only reaches public
+	// addresses and doesn't follow redirects.
+	Client *http.Client
+}
+
+// Dispatcher POSTs pending webhook deliveries, retrying failures with
+// exponential backoff until they run out of attempts
+type Dispatcher struct {
+	store       interfaces.WebhookDeliveries
+	client      *http.Client
+	logger      *slog.Logger
+	secret      string
+	maxAttempts int
+	timeout     time.Duration
+	interval    time.Duration
+	batchSize   int
+	lease       time.Duration
+}
+
+func NewDispatcher(store interfaces.WebhookDeliveries, logger *slog.Logger, opts Options) (*Dispatcher, error) {
+	if opts.Secret == "" {
+		return nil, ErrNoSecret
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	client := opts.Client
+	if client == nil {
+		client = newClient()
+	}
+	return &Dispatcher{
+		store:       store,
+		client:      client,
+		logger:      logger,
+		secret:      opts.Secret,
+		maxAttempts: opts.MaxAttempts,
+		timeout:     opts.Timeout,
+		interval:    opts.Interval,
+		batchSize:   opts.BatchSize,
+		// Claimed deliveries stay hidden until well after the request times out
+		lease: opts.Timeout + 30*time.Second,
+	}, nil
+}
+
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.logger.Info("Webhook dispatcher started")
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep draining while batches come back full
+		for {
+			n, err := d.dispatchBatch(ctx)
+			if err != nil {
+				d.logger.Error("failed to claim webhook deliveries", "error", err)
+				break
+			}
+			if n < d.batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.logger.Info("Webhook dispatcher shutting down")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchBatch sends a batch of due deliveries concurrently, so one slow
+// receiver doesn't hold up the rest
+func (d *Dispatcher) dispatchBatch(ctx context.Context) (int, error) {
+	deliveries, err := d.store.ClaimWebhookDeliveries(d.batchSize, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver makes one attempt at delivery and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+	delivery.ResponseStatus = status
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = models.WebhookStatusFailed
+		delivery.LastError = err.Error()
+		d.logger.Warn("webhook delivery failed, giving up",
+			"error", err, "delivery_id", delivery.ID, "job_id", delivery.JobID, "attempts", delivery.Attempts)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+		d.logger.Warn("webhook delivery failed, will retry",
+			"error", err, "delivery_id", delivery.ID, "job_id", delivery.JobID,
+			"attempts", delivery.Attempts, "next_attempt_at", delivery.NextAttemptAt)
+	}
+	metrics.WebhookDeliveries.WithLabelValues(string(delivery.Status)).Inc()
+
+	// A failure here only causes a repeat attempt once the lease expires
+	if err := d.store.UpdateWebhookDelivery(delivery); err != nil {
+		d.logger.Error("failed to record webhook delivery", "error", err, "delivery_id", delivery.ID)
+	}
+}
+
+// send POSTs the delivery's payload, signed, and returns the response status.
+// Anything but a 2xx response is an error.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dab-job-platform-webhooks")
+	req.Header.Set(HeaderID, delivery.ID.String())
+	req.Header.Set(HeaderEvent, delivery.Event)
+	now := time.Now()
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+	req.Header.Set(HeaderSignature, Sign(d.secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// Backoff returns the delay before the next attempt at a delivery, doubling
+// per attempt up to maxBackoff
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
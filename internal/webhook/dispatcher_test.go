@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+// finishJob creates a job calling back url and completes it
+func finishJob(t *testing.T, repo *repository.MemoryRepository, url string) *models.Job {
+	t.Helper()
+
+	job := &models.Job{Type: "report", CallbackURL: url}
+	require.NoError(t, repo.CreateJob(job))
+	job.Status = models.JobStatusCompleted
+	job.Attempts = 1
+	job.Result = &models.JobResult{Message: "done"}
+	require.NoError(t, repo.UpdateJob(job))
+	return job
+}
+
+func TestDispatcher_Delivers(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	job := finishJob(t, repo, server.URL)
+	d, err := NewDispatcher(repo, slog.Default(), Options{Secret: "secret", Client: server.Client()})
+	require.NoError(t, err)
+
+	n, err := d.dispatchBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	req := <-received
+	assert.Equal(t, "job.completed", req.Header.Get(HeaderEvent))
+	assert.True(t, Verify("secret", req.Header.Get(HeaderSignature), req.Header.Get(HeaderTimestamp), body, time.Minute, time.Now()))
+
+	var event models.WebhookEvent
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, job.ID, event.JobID)
+	assert.Equal(t, models.JobStatusCompleted, event.Status)
+	assert.Equal(t, "done", event.Result.Message)
+
+	deliveries, err := repo.ListWebhookDeliveries(models.WebhookFilter{JobID: &job.ID})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.WebhookStatusDelivered, deliveries[0].Status)
+	assert.Equal(t, req.Header.Get(HeaderID), deliveries[0].ID.String())
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+
+	// Nothing is left to send
+	n, err = d.dispatchBatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestDispatcher_RetriesThenFails(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	job := finishJob(t, repo, server.URL)
+	d, err := NewDispatcher(repo, slog.Default(), Options{Secret: "secret", MaxAttempts: 2, Client: server.Client()})
+	require.NoError(t, err)
+
+	_, err = d.dispatchBatch(context.Background())
+	require.NoError(t, err)
+
+	deliveries, err := repo.ListWebhookDeliveries(models.WebhookFilter{JobID: &job.ID})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, models.WebhookStatusPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseStatus)
+	assert.Contains(t, delivery.LastError, "down for maintenance")
+	assert.WithinDuration(t, time.Now().Add(Backoff(1)), delivery.NextAttemptAt, time.Second)
+
+	// Not due again until the backoff passes
+	n, err := d.dispatchBatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	delivery.NextAttemptAt = time.Now()
+	require.NoError(t, repo.UpdateWebhookDelivery(&delivery))
+	_, err = d.dispatchBatch(context.Background())
+	require.NoError(t, err)
+
+	deliveries, err = repo.ListWebhookDeliveries(models.WebhookFilter{JobID: &job.ID})
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookStatusFailed, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestNewDispatcher_RequiresSecret(t *testing.T) {
+	_, err := NewDispatcher(repository.NewMemoryRepository(), slog.Default(), Options{})
+	assert.ErrorIs(t, err, ErrNoSecret)
+}
+
+func TestDispatcher_RefusesInternalAddresses(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	job := finishJob(t, repo, server.URL)
+	d, err := NewDispatcher(repo, slog.Default(), Options{Secret: "secret"})
+	require.NoError(t, err)
+
+	_, err = d.dispatchBatch(context.Background())
+	require.NoError(t, err)
+
+	deliveries, err := repo.ListWebhookDeliveries(models.WebhookFilter{JobID: &job.ID})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.WebhookStatusPending, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].LastError, ErrBlockedAddress.Error())
+	assert.Zero(t, calls.Load())
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	var followed atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			followed.Add(1)
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	defer server.Close()
+
+	// The default client, but able to reach the test server
+	client := newClient()
+	client.Transport = server.Client().Transport
+
+	repo := repository.NewMemoryRepository()
+	job := finishJob(t, repo, server.URL)
+	d, err := NewDispatcher(repo, slog.Default(), Options{Secret: "secret", Client: client})
+	require.NoError(t, err)
+
+	_, err = d.dispatchBatch(context.Background())
+	require.NoError(t, err)
+
+	deliveries, err := repo.ListWebhookDeliveries(models.WebhookFilter{JobID: &job.ID})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.WebhookStatusPending, deliveries[0].Status)
+	assert.Equal(t, http.StatusFound, deliveries[0].ResponseStatus)
+	assert.Zero(t, followed.Load())
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, baseBackoff, Backoff(0))
+	assert.Equal(t, baseBackoff, Backoff(1))
+	assert.Equal(t, 4*baseBackoff, Backoff(3))
+	assert.Equal(t, maxBackoff, Backoff(20))
+}
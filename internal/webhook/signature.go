@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderID        = "X-Webhook-ID"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns the signature header value for body sent at timestamp: the
+// hex HMAC-SHA256, keyed with secret, of the Unix timestamp, a dot and the
+// body. Covering the timestamp lets receivers reject replayed requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature, timestamp and body are the headers and
+// body of a notification signed with secret, sent no more than tolerance
+// before now
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	sentAt := time.Unix(seconds, 0)
+	if now.Sub(sentAt) > tolerance || sentAt.Sub(now) > tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, sentAt, body)))
+}
@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"event":"job.completed"}`)
+	signature := Sign("secret", now, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		want      bool
+	}{
+		{"valid", "secret", signature, timestamp, body, true},
+		{"wrong secret", "other", signature, timestamp, body, false},
+		{"tampered body", "secret", signature, timestamp, []byte(`{"event":"job.failed"}`), false},
+		{"other timestamp", "secret", signature, strconv.FormatInt(now.Unix()-1, 10), body, false},
+		{"stale", "secret", Sign("secret", now.Add(-10*time.Minute), body), strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10), body, false},
+		{"bad timestamp", "secret", signature, "yesterday", body, false},
+		{"missing prefix", "secret", signature[len("sha256="):], timestamp, body, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Verify(tt.secret, tt.signature, tt.timestamp, tt.body, 5*time.Minute, now))
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing/tracingtest"
+)
+
+func countingRegistry(calls *atomic.Int32) *jobs.Registry {
+	registry := jobs.NewRegistry()
+	registry.Register("count", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		calls.Add(1)
+		return &models.JobResult{ProcessedAt: time.Now(), Message: "run"}, nil
+	}))
+	return registry
+}
+
+func TestProcessor_RedeliveredCompletedJobIsNotRerun(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	var calls atomic.Int32
+	p := NewProcessor(repo, q, countingRegistry(&calls), slog.New(slog.NewTextHandler(io.Discard, nil)), Options{WorkerID: "worker-a"})
+
+	job, msg := receiveOne(t, repo, q, "count")
+	require.NoError(t, p.processMessage(context.Background(), msg))
+
+	done, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, done.Status)
+	assert.Equal(t, "worker-a", done.WorkerID)
+	assert.Nil(t, done.LeaseExpiresAt, "lease released when the job finishes")
+
+	// A duplicate delivery is acknowledged without running the handler
+	require.NoError(t, q.SendMessage(context.Background(), job.ID.String()))
+	duplicate, err := q.ReceiveMessages(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, duplicate, 1)
+	require.NoError(t, p.processMessage(context.Background(), duplicate[0]))
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 0, q.Len())
+	again, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, done.Result, again.Result)
+	assert.Equal(t, 1, again.Attempts)
+}
+
+func TestProcessor_RecordsProcessedJobs(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	var calls atomic.Int32
+	p := NewProcessor(repo, q, countingRegistry(&calls), slog.New(slog.NewTextHandler(io.Discard, nil)), Options{WorkerID: "worker-a"})
+	completed := metrics.JobsProcessed.WithLabelValues("count", string(models.JobStatusCompleted))
+	before := testutil.ToFloat64(completed)
+
+	job, msg := receiveOne(t, repo, q, "count")
+	require.NoError(t, p.processMessage(context.Background(), msg))
+	assert.Equal(t, before+1, testutil.ToFloat64(completed))
+
+	// Duplicate deliveries of finished jobs aren't processing attempts
+	require.NoError(t, q.SendMessage(context.Background(), job.ID.String()))
+	duplicate, err := q.ReceiveMessages(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, duplicate, 1)
+	require.NoError(t, p.processMessage(context.Background(), duplicate[0]))
+	assert.Equal(t, before+1, testutil.ToFloat64(completed))
+}
+
+func TestProcessor_ContinuesTrace(t *testing.T) {
+	spans := tracingtest.Record(t)
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	var calls atomic.Int32
+	p := NewProcessor(repo, q, countingRegistry(&calls), slog.New(slog.NewTextHandler(io.Discard, nil)), Options{WorkerID: "worker-a"})
+
+	job := &models.Job{Type: "count"}
+	require.NoError(t, repo.CreateJob(job))
+	ctx, submit := tracing.Tracer().Start(context.Background(), "submit")
+	require.NoError(t, q.SendMessage(ctx, job.ID.String()))
+	submit.End()
+
+	messages, err := q.ReceiveMessages(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	require.NoError(t, p.processMessage(context.Background(), messages[0]))
+
+	processed := tracingtest.Find(spans, "process job")
+	require.NotNil(t, processed)
+	assert.Equal(t, submit.SpanContext().TraceID(), processed.SpanContext.TraceID())
+	assert.Equal(t, submit.SpanContext().SpanID(), processed.Parent.SpanID())
+	assert.Contains(t, processed.Attributes, attribute.String("job.id", job.ID.String()))
+	assert.Contains(t, processed.Attributes, attribute.String("job.status", string(models.JobStatusCompleted)))
+}
+
+func TestProcessor_JobLeasedByAnotherWorkerIsDeferred(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	var calls atomic.Int32
+	p := NewProcessor(repo, q, countingRegistry(&calls), slog.New(slog.NewTextHandler(io.Discard, nil)), Options{WorkerID: "worker-a"})
+
+	job, msg := receiveOne(t, repo, q, "count")
+	_, err := repo.ClaimJob(job.ID.String(), "worker-b", time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, p.processMessage(context.Background(), msg))
+	assert.Equal(t, int32(0), calls.Load())
+
+	// The message stays queued, hidden until worker-b's lease runs out
+	assert.Equal(t, 1, q.Len())
+	hidden, err := q.ReceiveMessages(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, hidden)
+
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "worker-b", found.WorkerID)
+	assert.Equal(t, models.JobStatusProcessing, found.Status)
+}
+
+func TestProcessor_CancelRunningJob(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	started := make(chan struct{})
+	var cause error
+	registry := jobs.NewRegistry()
+	registry.Register("long", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		close(started)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+		return nil, ctx.Err()
+	}))
+
+	p := NewProcessor(repo, q, registry, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		CancelPollInterval: 10 * time.Millisecond,
+	})
+
+	job, msg := receiveOne(t, repo, q, "long")
+	done := make(chan error, 1)
+	go func() { done <- p.processMessage(context.Background(), msg) }()
+
+	<-started
+	_, err := repo.CancelJob(job.ID.String())
+	require.NoError(t, err)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("handler was not cancelled")
+	}
+
+	assert.ErrorIs(t, cause, ErrJobCancelled)
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCancelled, found.Status)
+	assert.NotNil(t, found.CancelRequestedAt)
+	assert.Equal(t, 0, q.Len(), "cancelled jobs are not retried")
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// heartbeat calls beat every interval until the returned stop func is
+// called. It keeps long-running jobs' message visibility and job lease from
+// expiring, so they aren't handed to another worker, and watches for
+// cancellation. Failed beats are logged as what; stop may be called more
+// than once.
+func (p *Processor) heartbeat(ctx context.Context, what string, interval time.Duration, beat func(ctx context.Context) error) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := beat(ctx); err != nil && ctx.Err() == nil {
+					p.logger.Warn("heartbeat failed", "heartbeat", what, "error", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
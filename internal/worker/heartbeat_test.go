@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+)
+
+// receiveOne creates a job and receives its message from q
+func receiveOne(t *testing.T, repo *repository.MemoryRepository, q *queue.MemoryQueue, jobType string) (*models.Job, queue.Message) {
+	t.Helper()
+
+	job := &models.Job{Type: jobType}
+	require.NoError(t, repo.CreateJob(job))
+	require.NoError(t, q.SendMessage(context.Background(), job.ID.String()))
+
+	messages, err := q.ReceiveMessages(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	return job, messages[0]
+}
+
+func TestProcessor_HeartbeatKeepsMessageHidden(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(50*time.Millisecond, 0)
+	q.SetWaitTime(0)
+
+	registry := jobs.NewRegistry()
+	registry.Register("slow", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		time.Sleep(200 * time.Millisecond)
+		return &models.JobResult{ProcessedAt: time.Now()}, nil
+	}))
+
+	p := NewProcessor(repo, q, registry, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		VisibilityTimeout: 50 * time.Millisecond,
+		HeartbeatInterval: 10 * time.Millisecond,
+	})
+
+	job, msg := receiveOne(t, repo, q, "slow")
+
+	done := make(chan error, 1)
+	go func() { done <- p.processMessage(context.Background(), msg) }()
+
+	// Well past the original visibility timeout, nobody else gets the message
+	for i := 0; i < 5; i++ {
+		time.Sleep(30 * time.Millisecond)
+		redelivered, err := q.ReceiveMessages(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Empty(t, redelivered)
+	}
+
+	require.NoError(t, <-done)
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, found.Status)
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestProcessor_MaxRuntime(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(0)
+
+	registry := jobs.NewRegistry()
+	registry.Register("stuck", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	p := NewProcessor(repo, q, registry, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		MaxRuntime: 20 * time.Millisecond,
+	})
+
+	job, msg := receiveOne(t, repo, q, "stuck")
+	require.NoError(t, p.processMessage(context.Background(), msg))
+
+	// Overrunning is permanent even though attempts remain
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, found.Status)
+	assert.Contains(t, found.Error, ErrMaxRuntimeExceeded.Error())
+	assert.Equal(t, 1, found.Attempts)
+	assert.Equal(t, 0, q.Len())
+}
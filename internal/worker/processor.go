@@ -2,262 +2,520 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
-	"github.com/google/uuid"
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/interfaces"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/metrics"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/tracing"
+)
+
+const (
+	// defaultJobLease is the job lease used when there is no visibility
+	// timeout to match it to
+	defaultJobLease = 5 * time.Minute
+
+	defaultCancelPollInterval = 5 * time.Second
+)
+
+var (
+	// ErrMaxRuntimeExceeded fails jobs whose handler runs past the max runtime
+	ErrMaxRuntimeExceeded = errors.New("job exceeded max runtime")
+	// ErrJobCancelled is the cause of a handler's context when the job is cancelled
+	ErrJobCancelled = errors.New("job cancelled")
 )
 
 type Processor struct {
-	db     *gorm.DB
-	queue  *queue.SQSClient
-	logger *slog.Logger
+	workerID string
+	repo     interfaces.Repository
+	queue    interfaces.Queue
+	registry *jobs.Registry
+	logger   *slog.Logger
+
+	concurrency int
+	batchSize   int
+	types       *typeLimits
+
+	visibilityTimeout  time.Duration
+	heartbeatInterval  time.Duration
+	maxRuntime         time.Duration
+	cancelPollInterval time.Duration
+	retryBaseDelay     time.Duration
 }
 
-func NewProcessor(db *gorm.DB, queue *queue.SQSClient, logger *slog.Logger) *Processor {
-	return &Processor{
-		db:     db,
-		queue:  queue,
-		logger: logger,
+// Options configures the processor worker pool
+type Options struct {
+	// WorkerID identifies this worker on the jobs it claims; it defaults
+	// to the hostname and process ID
+	WorkerID string
+	// Concurrency is the number of jobs processed in parallel
+	Concurrency int
+	// BatchSize caps how many messages are received per poll
+	BatchSize int
+	// TypeConcurrency caps parallel jobs per job type; types not listed
+	// are only bounded by Concurrency. Up to Concurrency messages of a type
+	// at its cap wait without taking up workers, and further ones go back
+	// to the queue for a while.
+	TypeConcurrency map[string]int
+	// VisibilityTimeout is how long each heartbeat keeps an in-flight
+	// message hidden, and how long jobs are leased for; zero disables
+	// visibility heartbeats and leases jobs for 5m
+	VisibilityTimeout time.Duration
+	// HeartbeatInterval is how often in-flight messages are extended and
+	// job leases renewed. It defaults to a third of the lease and must be
+	// shorter than it.
+	HeartbeatInterval time.Duration
+	// MaxRuntime cancels a handler's context after this long; zero means
+	// no limit
+	MaxRuntime time.Duration
+	// CancelPollInterval is how often running jobs are checked for a
+	// cancellation request
+	CancelPollInterval time.Duration
+	// RetryBaseDelay is the backoff before a failed job's first retry,
+	// doubling for each further retry; it defaults to 10s
+	RetryBaseDelay time.Duration
+}
+
+func NewProcessor(repo interfaces.Repository, queue interfaces.Queue, registry *jobs.Registry, logger *slog.Logger, opts Options) *Processor {
+	if opts.WorkerID == "" {
+		opts.WorkerID = defaultWorkerID()
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10
+	}
+	if opts.CancelPollInterval <= 0 {
+		opts.CancelPollInterval = defaultCancelPollInterval
+	}
+	if opts.RetryBaseDelay <= 0 {
+		opts.RetryBaseDelay = defaultRetryBaseDelay
+	}
+
+	p := &Processor{
+		workerID:    opts.WorkerID,
+		repo:        repo,
+		queue:       queue,
+		registry:    registry,
+		logger:      logger,
+		concurrency: opts.Concurrency,
+		batchSize:   opts.BatchSize,
+		types:       newTypeLimits(opts.TypeConcurrency),
+
+		visibilityTimeout:  opts.VisibilityTimeout,
+		heartbeatInterval:  opts.HeartbeatInterval,
+		maxRuntime:         opts.MaxRuntime,
+		cancelPollInterval: opts.CancelPollInterval,
+		retryBaseDelay:     opts.RetryBaseDelay,
+	}
+	// Leases need renewing even on queues without a visibility timeout
+	if p.heartbeatInterval <= 0 || p.heartbeatInterval >= p.lease() {
+		p.heartbeatInterval = p.lease() / 3
 	}
+	return p
 }
 
+// Start runs the receive loop and the worker pool until ctx is cancelled,
+// then waits for in-flight jobs to finish. Messages are only received when
+// a worker is free to take them, so nothing sits in memory while its
+// visibility timeout runs down.
 func (p *Processor) Start(ctx context.Context) error {
-	p.logger.Info("Worker started")
+	p.logger.Info("Worker started", "concurrency", p.concurrency)
+
+	messages := make(chan queue.Message)
+	slots := make(chan struct{}, p.concurrency)
+
+	// In-flight jobs are allowed to finish after shutdown is requested
+	workCtx := context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range messages {
+				p.run(workCtx, msg)
+				<-slots
+			}
+		}()
+	}
+
+	p.receiveLoop(ctx, messages, slots)
 
+	p.logger.Info("Worker shutting down, waiting for in-flight jobs")
+	// Messages waiting for their type go back for other workers to take
+	for _, msg := range p.types.close() {
+		if err := p.queue.Nack(workCtx, msg, 0); err != nil {
+			p.logger.Error("failed to return waiting message", "error", err, "message_id", msg.ID)
+		}
+		metrics.JobsInFlight.Dec()
+	}
+	close(messages)
+	wg.Wait()
+	return nil
+}
+
+// run processes msg on a worker, then any messages waiting for a job type
+// that has room again, such as the type of the job just finished
+func (p *Processor) run(ctx context.Context, msg queue.Message) {
 	for {
+		metrics.JobsInFlight.Inc()
+		if err := p.processMessage(ctx, msg); err != nil {
+			p.logger.Error("failed to process message", "error", err)
+		}
+		metrics.JobsInFlight.Dec()
+
+		next, ok := p.types.next()
+		if !ok {
+			return
+		}
+		// It was counted in flight while it waited
+		metrics.JobsInFlight.Dec()
+		msg = next
+	}
+}
+
+func (p *Processor) receiveLoop(ctx context.Context, messages chan<- queue.Message, slots chan struct{}) {
+	for {
+		// Block until at least one worker is free
 		select {
 		case <-ctx.Done():
-			p.logger.Info("Worker shutting down")
-			return nil
-		default:
-			messages, err := p.queue.ReceiveMessages(ctx)
-			if err != nil {
-				p.logger.Error("failed to receive messages", "error", err)
-				time.Sleep(5 * time.Second)
-				continue
-			}
+			return
+		case slots <- struct{}{}:
+		}
+
+		free := p.acquireFreeSlots(slots)
 
-			for _, msg := range messages {
-				if err := p.processMessage(ctx, msg); err != nil {
-					p.logger.Error("failed to process message", "error", err)
-				}
+		received, err := p.queue.ReceiveMessages(ctx, free)
+		if err != nil {
+			releaseSlots(slots, free)
+			if ctx.Err() != nil {
+				return
 			}
+			p.logger.Error("failed to receive messages", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		releaseSlots(slots, free-len(received))
+		for _, msg := range received {
+			observeQueueLatency(msg)
+			messages <- msg
 		}
 	}
 }
 
-func (p *Processor) processMessage(ctx context.Context, msg types.Message) error {
-	if msg.Body == nil {
-		return fmt.Errorf("message body is nil")
+// acquireFreeSlots takes any additional idle slots without blocking, up to
+// the receive batch size, and returns the total held including the one
+// already acquired.
+func (p *Processor) acquireFreeSlots(slots chan struct{}) int {
+	held := 1
+	for held < p.batchSize {
+		select {
+		case slots <- struct{}{}:
+			held++
+		default:
+			return held
+		}
+	}
+	return held
+}
+
+// observeQueueLatency records how long msg waited to be received. Messages
+// from a single queue backend carry no priority and count as normal.
+func observeQueueLatency(msg queue.Message) {
+	if msg.EnqueuedAt.IsZero() {
+		return
 	}
-	
-	var jobMsg queue.JobMessage
-	if err := json.Unmarshal([]byte(*msg.Body), &jobMsg); err != nil {
-		return fmt.Errorf("failed to unmarshal message: %w", err)
+	priority := msg.Priority
+	if priority == "" {
+		priority = models.JobPriorityNormal
 	}
+	metrics.QueueLatency.WithLabelValues(string(priority)).Observe(time.Since(msg.EnqueuedAt).Seconds())
+}
 
-	jobID, err := uuid.Parse(jobMsg.JobID)
-	if err != nil {
-		return fmt.Errorf("invalid job ID: %w", err)
+func releaseSlots(slots chan struct{}, n int) {
+	for i := 0; i < n; i++ {
+		<-slots
 	}
+}
 
-	var job models.Job
-	if err := p.db.First(&job, "id = ?", jobID).Error; err != nil {
-		return fmt.Errorf("failed to find job: %w", err)
+// processMessage runs the job msg refers to, continuing the trace of the
+// request that submitted it
+func (p *Processor) processMessage(ctx context.Context, msg queue.Message) error {
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, msg.Attributes), "process job",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("messaging.message.id", msg.ID)),
+	)
+	defer span.End()
+
+	if err := p.handleMessage(ctx, msg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
 	}
+	return nil
+}
 
-	job.Status = models.JobStatusProcessing
-	if err := p.db.Save(&job).Error; err != nil {
-		return fmt.Errorf("failed to update job status: %w", err)
+func (p *Processor) handleMessage(ctx context.Context, msg queue.Message) error {
+	span := trace.SpanFromContext(ctx)
+	// Queries are traced but not cancelled with ctx, so the outcome is still
+	// saved during shutdown. Heartbeats keep using p.repo, so their polling
+	// stays out of the trace.
+	repo := repository.WithContext(context.WithoutCancel(ctx), p.repo)
+
+	stopHeartbeat := p.holdMessage(ctx, msg)
+	defer stopHeartbeat()
+
+	jobMsg, err := msg.JobMessage()
+	if err != nil {
+		return err
 	}
 
-	p.logger.Info("Processing job", "job_id", job.ID)
+	span.SetAttributes(attribute.String("job.id", jobMsg.JobID))
 
-	result, err := p.processJob(&job)
+	found, err := repo.GetJob(jobMsg.JobID)
 	if err != nil {
-		job.Status = models.JobStatusFailed
-		job.Error = err.Error()
+		return fmt.Errorf("failed to find job %s: %w", jobMsg.JobID, err)
+	}
+	span.SetAttributes(attribute.String("job.type", found.Type))
+	if found.IsFinished() {
+		stopHeartbeat()
+		return p.skipJob(ctx, msg, found)
+	}
+
+	release, admission := p.types.admit(found.Type, msg, p.concurrency, func() func() {
+		// Waiting messages are kept hidden until a worker takes them on
+		metrics.JobsInFlight.Inc()
+		return p.holdMessage(context.WithoutCancel(ctx), msg)
+	})
+	switch admission {
+	case parked:
+		p.logger.Info("Job type at its concurrency limit, job waiting", "job_id", found.ID, "type", found.Type)
+		return nil
+	case refused:
+		stopHeartbeat()
+		p.logger.Info("Job type at its concurrency limit, deferring message",
+			"job_id", found.ID, "type", found.Type, "retry_in", typeBusyDelay)
+		if err := p.queue.Nack(ctx, msg, typeBusyDelay); err != nil {
+			return fmt.Errorf("failed to nack message: %w", err)
+		}
+		return nil
+	}
+	defer release()
+
+	claimed, err := repo.ClaimJob(jobMsg.JobID, p.workerID, p.lease())
+	if errors.Is(err, repository.ErrJobNotClaimable) {
+		stopHeartbeat()
+		if found, err = repo.GetJob(jobMsg.JobID); err != nil {
+			return fmt.Errorf("failed to find job %s: %w", jobMsg.JobID, err)
+		}
+		return p.skipJob(ctx, msg, found)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim job %s: %w", jobMsg.JobID, err)
+	}
+	job := *claimed
+
+	stopLease := p.heartbeat(ctx, "lease", p.heartbeatInterval, func(ctx context.Context) error {
+		return p.repo.RenewJobLease(job.ID.String(), p.workerID, p.lease())
+	})
+	defer stopLease()
+
+	// Cancellation requests are picked up by polling the job; the handler
+	// sees them as its context being cancelled
+	jobCtx, cancelJob := context.WithCancelCause(ctx)
+	defer cancelJob(nil)
+	if job.CancelRequestedAt != nil {
+		cancelJob(ErrJobCancelled)
+	}
+	var cancelRequestedAt *time.Time
+	stopWatch := p.heartbeat(jobCtx, "cancellation", p.cancelPollInterval, func(ctx context.Context) error {
+		current, err := p.repo.GetJob(job.ID.String())
+		if err != nil {
+			return err
+		}
+		if current.CancelRequestedAt != nil {
+			cancelRequestedAt = current.CancelRequestedAt
+			cancelJob(ErrJobCancelled)
+		}
+		return nil
+	})
+	defer stopWatch()
+
+	attempt := models.JobAttempt{Attempt: job.Attempts, StartedAt: time.Now()}
+
+	p.logger.Info("Processing job", "job_id", job.ID, "attempt", job.Attempts)
+
+	var retryIn time.Duration
+	var result *models.JobResult
+	if jobCtx.Err() == nil {
+		result, err = p.runJob(jobCtx, &job)
 	} else {
+		err = context.Cause(jobCtx)
+	}
+	stopWatch()
+	if cancelRequestedAt != nil {
+		job.CancelRequestedAt = cancelRequestedAt
+	}
+	attempt.FinishedAt = time.Now()
+	switch {
+	case err == nil:
 		job.Status = models.JobStatusCompleted
 		job.Result = result
+		job.Error = ""
+	case errors.Is(context.Cause(jobCtx), ErrJobCancelled):
+		job.Status = models.JobStatusCancelled
+		job.Error = ErrJobCancelled.Error()
+	case job.CanRetry() && !jobs.IsPermanent(err):
+		job.Status = models.JobStatusRetrying
+		job.Error = err.Error()
+		retryIn = retryDelay(p.retryBaseDelay, job.Attempts)
+	default:
+		job.Status = models.JobStatusFailed
+		job.Error = err.Error()
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+		span.RecordError(err)
+	}
+	span.SetAttributes(attribute.Int("job.attempt", job.Attempts), attribute.String("job.status", string(job.Status)))
+	if job.Status == models.JobStatusFailed {
+		span.SetStatus(codes.Error, job.Error)
 	}
+	job.AttemptHistory = append(job.AttemptHistory, attempt)
+	metrics.JobsProcessed.WithLabelValues(job.Type, string(job.Status)).Inc()
+	metrics.JobDuration.WithLabelValues(job.Type, string(job.Status)).Observe(attempt.FinishedAt.Sub(attempt.StartedAt).Seconds())
 
-	if err := p.db.Save(&job).Error; err != nil {
+	stopLease()
+	job.LeaseExpiresAt = nil
+
+	// A job whose lease ran out may be running on another worker or have
+	// been cancelled. Its message is left to come back after the visibility
+	// timeout and be skipped or deferred like any other redelivery.
+	if err := repo.FinishJob(&job); err != nil {
+		if errors.Is(err, repository.ErrLeaseLost) {
+			p.logger.Warn("Job lease lost, discarding result", "job_id", job.ID, "status", job.Status)
+			return nil
+		}
 		return fmt.Errorf("failed to update job result: %w", err)
 	}
 
-	if msg.ReceiptHandle != nil {
-		if err := p.queue.DeleteMessage(ctx, *msg.ReceiptHandle); err != nil {
-			return fmt.Errorf("failed to delete message: %w", err)
+	// Retries go out as a new delayed message. If that fails the original
+	// is left on the queue and comes back after its visibility timeout.
+	if job.Status == models.JobStatusRetrying {
+		if err := p.queue.SendMessage(ctx, job.ID.String(), queue.WithDelay(retryIn), queue.WithPriority(job.Priority)); err != nil {
+			return fmt.Errorf("failed to requeue job for retry: %w", err)
 		}
+		p.logger.Warn("Job failed, retry scheduled",
+			"job_id", job.ID, "attempt", job.Attempts, "max_attempts", job.MaxAttempts, "retry_in", retryIn, "error", job.Error)
+	}
+
+	stopHeartbeat()
+	if err := p.queue.Ack(ctx, msg); err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
 	}
 
 	p.logger.Info("Job processed", "job_id", job.ID, "status", job.Status)
 	return nil
 }
 
-func (p *Processor) processJob(job *models.Job) (*models.JobResult, error) {
-	p.logger.Info("Processing job", "type", job.Type, "id", job.ID)
-	
-	startTime := time.Now()
-	
-	switch job.Type {
-	case "cleanup":
-		return p.processCleanupJob(job)
-	case "health-report":
-		return p.processHealthReportJob(job)
-	case "data-aggregation":
-		return p.processDataAggregationJob(job)
-	case "batch-import":
-		return p.processBatchImportJob(job)
-	case "data-processing":
-		return p.processDataJob(job)
-	default:
-		// Generic processing for unknown types
-		time.Sleep(1 * time.Second)
-		return &models.JobResult{
-			ProcessedAt: time.Now(),
-			InputCount:  len(job.Data),
-			Message:     fmt.Sprintf("Job of type '%s' processed in %v", job.Type, time.Since(startTime)),
-		}, nil
+// skipJob handles a message for a job this worker couldn't claim. Finished
+// jobs are acknowledged without running again. A job another worker holds
+// is left on the queue until that worker's lease runs out, so the job is
+// picked up again if the other worker died.
+func (p *Processor) skipJob(ctx context.Context, msg queue.Message, job *models.Job) error {
+	if job.IsFinished() {
+		p.logger.Info("Job already finished, skipping redelivery", "job_id", job.ID, "status", job.Status)
+		if err := p.queue.Ack(ctx, msg); err != nil {
+			return fmt.Errorf("failed to ack message: %w", err)
+		}
+		return nil
 	}
+
+	var retryIn time.Duration
+	if job.LeaseExpiresAt != nil {
+		retryIn = time.Until(*job.LeaseExpiresAt)
+	}
+	if retryIn < time.Second {
+		retryIn = time.Second
+	}
+	p.logger.Info("Job claimed by another worker, deferring message",
+		"job_id", job.ID, "status", job.Status, "worker_id", job.WorkerID, "retry_in", retryIn)
+	if err := p.queue.Nack(ctx, msg, retryIn); err != nil {
+		return fmt.Errorf("failed to nack message: %w", err)
+	}
+	return nil
 }
 
-func (p *Processor) processCleanupJob(job *models.Job) (*models.JobResult, error) {
-	// Delete completed jobs older than 7 days
-	cutoffDate := time.Now().AddDate(0, 0, -7)
-	
-	var deletedCount int64
-	err := p.db.Model(&models.Job{}).
-		Where("status = ? AND updated_at < ?", models.JobStatusCompleted, cutoffDate).
-		Count(&deletedCount).
-		Delete(&models.Job{}).Error
-		
-	if err != nil {
-		return nil, fmt.Errorf("failed to cleanup old jobs: %w", err)
-	}
-	
-	return &models.JobResult{
-		ProcessedAt: time.Now(),
-		InputCount:  int(deletedCount),
-		Message:     fmt.Sprintf("Cleaned up %d old completed jobs", deletedCount),
-	}, nil
+// holdMessage keeps msg hidden with a heartbeat until the returned stop func
+// is called. Queues without a visibility timeout need none.
+func (p *Processor) holdMessage(ctx context.Context, msg queue.Message) (stop func()) {
+	if p.visibilityTimeout <= 0 {
+		return func() {}
+	}
+	return p.heartbeat(ctx, "visibility", p.heartbeatInterval, func(ctx context.Context) error {
+		return p.queue.Extend(ctx, msg, p.visibilityTimeout)
+	})
 }
 
-func (p *Processor) processHealthReportJob(job *models.Job) (*models.JobResult, error) {
-	// Generate health metrics
-	var metrics struct {
-		TotalJobs      int64
-		PendingJobs    int64
-		ProcessingJobs int64
-		CompletedJobs  int64
-		FailedJobs     int64
-	}
-	
-	p.db.Model(&models.Job{}).Count(&metrics.TotalJobs)
-	p.db.Model(&models.Job{}).Where("status = ?", models.JobStatusPending).Count(&metrics.PendingJobs)
-	p.db.Model(&models.Job{}).Where("status = ?", models.JobStatusProcessing).Count(&metrics.ProcessingJobs)
-	p.db.Model(&models.Job{}).Where("status = ?", models.JobStatusCompleted).Count(&metrics.CompletedJobs)
-	p.db.Model(&models.Job{}).Where("status = ?", models.JobStatusFailed).Count(&metrics.FailedJobs)
-	
-	// In production, this would send to CloudWatch or S3
-	p.logger.Info("Health Report Generated",
-		"total", metrics.TotalJobs,
-		"pending", metrics.PendingJobs,
-		"processing", metrics.ProcessingJobs,
-		"completed", metrics.CompletedJobs,
-		"failed", metrics.FailedJobs,
-	)
-	
-	return &models.JobResult{
-		ProcessedAt: time.Now(),
-		InputCount:  int(metrics.TotalJobs),
-		Message:     fmt.Sprintf("Health report: Total=%d, Pending=%d, Processing=%d, Completed=%d, Failed=%d",
-			metrics.TotalJobs, metrics.PendingJobs, metrics.ProcessingJobs, metrics.CompletedJobs, metrics.FailedJobs),
-	}, nil
+// lease is how long a claim on a job lasts without a heartbeat
+func (p *Processor) lease() time.Duration {
+	if p.visibilityTimeout > 0 {
+		return p.visibilityTimeout
+	}
+	return defaultJobLease
 }
 
-func (p *Processor) processDataAggregationJob(job *models.Job) (*models.JobResult, error) {
-	// Aggregate daily statistics
-	yesterday := time.Now().AddDate(0, 0, -1)
-	startOfDay := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, yesterday.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-	
-	var dailyStats struct {
-		JobsCreated   int64
-		JobsCompleted int64
-		JobsFailed    int64
-		AvgProcessing float64
-	}
-	
-	p.db.Model(&models.Job{}).
-		Where("created_at BETWEEN ? AND ?", startOfDay, endOfDay).
-		Count(&dailyStats.JobsCreated)
-		
-	p.db.Model(&models.Job{}).
-		Where("status = ? AND updated_at BETWEEN ? AND ?", models.JobStatusCompleted, startOfDay, endOfDay).
-		Count(&dailyStats.JobsCompleted)
-		
-	p.db.Model(&models.Job{}).
-		Where("status = ? AND updated_at BETWEEN ? AND ?", models.JobStatusFailed, startOfDay, endOfDay).
-		Count(&dailyStats.JobsFailed)
-	
-	// In production, this would store in a metrics table or send to data warehouse
-	p.logger.Info("Daily aggregation completed",
-		"date", yesterday.Format("2006-01-02"),
-		"created", dailyStats.JobsCreated,
-		"completed", dailyStats.JobsCompleted,
-		"failed", dailyStats.JobsFailed,
-	)
-	
-	return &models.JobResult{
-		ProcessedAt: time.Now(),
-		InputCount:  int(dailyStats.JobsCreated),
-		Message:     fmt.Sprintf("Aggregated stats for %s: Created=%d, Completed=%d, Failed=%d",
-			yesterday.Format("2006-01-02"), dailyStats.JobsCreated, dailyStats.JobsCompleted, dailyStats.JobsFailed),
-	}, nil
+// runJob runs the job's handler under the max runtime. Handlers that
+// overrun fail permanently, since a retry would most likely overrun too.
+// Cancellation is cooperative: the handler must return once ctx is done.
+func (p *Processor) runJob(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+	if p.maxRuntime <= 0 {
+		return p.processJob(ctx, job)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.maxRuntime)
+	defer cancel()
+
+	result, err := p.processJob(ctx, job)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, jobs.Permanent(fmt.Errorf("%w of %s: %v", ErrMaxRuntimeExceeded, p.maxRuntime, err))
+	}
+	return result, err
 }
 
-func (p *Processor) processBatchImportJob(job *models.Job) (*models.JobResult, error) {
-	// Simulate processing batch import
-	// In production, this would:
-	// 1. Parse CSV/JSON from S3
-	// 2. Validate each record
-	// 3. Bulk insert into database
-	// 4. Generate import report
-	
-	recordCount := len(job.Data) / 10 // Simulate record count
-	time.Sleep(100 * time.Millisecond * time.Duration(recordCount))
-	
-	return &models.JobResult{
-		ProcessedAt: time.Now(),
-		InputCount:  recordCount,
-		Message:     fmt.Sprintf("Batch import completed: %d records processed", recordCount),
-	}, nil
+func (p *Processor) processJob(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+	p.logger.Info("Processing job", "type", job.Type, "id", job.ID)
+
+	handler, err := p.registry.Lookup(job.Type)
+	if err != nil {
+		return nil, err
+	}
+	return handler.ProcessJob(ctx, job)
 }
 
-func (p *Processor) processDataJob(job *models.Job) (*models.JobResult, error) {
-	// Simulate data processing
-	// In production, this could involve:
-	// - ETL operations
-	// - API calls to external services
-	// - Complex calculations
-	// - Report generation
-	
-	processingTime := time.Duration(len(job.Data)*10) * time.Millisecond
-	time.Sleep(processingTime)
-	
-	return &models.JobResult{
-		ProcessedAt: time.Now(),
-		InputCount:  len(job.Data),
-		Message:     fmt.Sprintf("Data processed successfully in %v", processingTime),
-	}, nil
-}
\ No newline at end of file
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
@@ -2,29 +2,45 @@ package worker
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
 	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
 )
 
 func TestProcessor_processJob(t *testing.T) {
+	registry := jobs.NewRegistry()
+	registry.Register("test-job", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		return &models.JobResult{
+			ProcessedAt: time.Now(),
+			InputCount:  len(job.Data),
+			Message:     "handled " + job.Type,
+		}, nil
+	}))
+
 	p := &Processor{
-		logger: slog.Default(),
+		registry: registry,
+		logger:   slog.Default(),
 	}
 
 	job := &models.Job{
 		ID:     uuid.New(),
 		Status: models.JobStatusProcessing,
 		Type:   "test-job",
-		Data:   "test data for processing",
+		Data:   models.JSONString("test data for processing"),
 	}
 
-	result, err := p.processJob(job)
+	result, err := p.processJob(context.Background(), job)
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.NotZero(t, result.ProcessedAt)
@@ -32,41 +48,23 @@ func TestProcessor_processJob(t *testing.T) {
 	assert.Contains(t, result.Message, "test-job")
 }
 
-func TestProcessor_processJob_DataProcessing(t *testing.T) {
+func TestProcessor_processJob_NoHandler(t *testing.T) {
 	p := &Processor{
-		logger: slog.Default(),
+		registry: jobs.NewRegistry(),
+		logger:   slog.Default(),
 	}
 
 	job := &models.Job{
 		ID:     uuid.New(),
 		Status: models.JobStatusProcessing,
-		Type:   "data-processing",
-		Data:   "sample data to process",
+		Type:   "unknown-type",
+		Data:   models.JSONString("test data"),
 	}
 
-	result, err := p.processJob(job)
-	assert.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.Equal(t, len(job.Data), result.InputCount)
-	assert.Contains(t, result.Message, "Data processed successfully")
-}
-
-func TestProcessor_processJob_BatchImport(t *testing.T) {
-	p := &Processor{
-		logger: slog.Default(),
-	}
-
-	job := &models.Job{
-		ID:     uuid.New(),
-		Status: models.JobStatusProcessing,
-		Type:   "batch-import",
-		Data:   "record1,record2,record3,record4,record5",
-	}
-
-	result, err := p.processJob(job)
-	assert.NoError(t, err)
-	assert.NotNil(t, result)
-	assert.Contains(t, result.Message, "Batch import completed")
+	result, err := p.processJob(context.Background(), job)
+	assert.ErrorIs(t, err, jobs.ErrNoHandler)
+	assert.Contains(t, err.Error(), "unknown-type")
+	assert.Nil(t, result)
 }
 
 func TestProcessor_Start_ContextCancellation(t *testing.T) {
@@ -83,6 +81,245 @@ func TestProcessor_Start_ContextCancellation(t *testing.T) {
 	// so we just verify the struct is properly initialized
 }
 
+func TestNewProcessor_Defaults(t *testing.T) {
+	p := NewProcessor(nil, nil, jobs.NewRegistry(), slog.Default(), Options{
+		TypeConcurrency: map[string]int{"batch-import": 2, "ignored": 0},
+	})
+
+	assert.Equal(t, 1, p.concurrency)
+	assert.Equal(t, 10, p.batchSize)
+	assert.Equal(t, map[string]int{"batch-import": 2}, p.types.limits)
+	assert.Equal(t, defaultJobLease/3, p.heartbeatInterval, "leases are renewed without a visibility timeout")
+
+	p = NewProcessor(nil, nil, jobs.NewRegistry(), slog.Default(), Options{
+		VisibilityTimeout: 5 * time.Minute,
+		HeartbeatInterval: 10 * time.Minute,
+	})
+	assert.Equal(t, 100*time.Second, p.heartbeatInterval, "interval must be shorter than the timeout")
+}
+
+func TestProcessor_acquireFreeSlots(t *testing.T) {
+	p := NewProcessor(nil, nil, jobs.NewRegistry(), slog.Default(), Options{Concurrency: 5, BatchSize: 3})
+	slots := make(chan struct{}, p.concurrency)
+
+	// One slot is held by the caller before asking for more
+	slots <- struct{}{}
+	assert.Equal(t, 3, p.acquireFreeSlots(slots), "capped by batch size")
+
+	slots <- struct{}{}
+	assert.Equal(t, 2, p.acquireFreeSlots(slots), "capped by free workers")
+
+	releaseSlots(slots, 5)
+	assert.Equal(t, 0, len(slots))
+}
+
+func TestTypeLimits(t *testing.T) {
+	limits := newTypeLimits(map[string]int{"batch-import": 1})
+	var held, stopped int
+	hold := func() func() {
+		held++
+		return func() { stopped++ }
+	}
+	msg := func(id string) queue.Message { return queue.Message{ID: id} }
+
+	release, admission := limits.admit("batch-import", msg("a"), 1, hold)
+	assert.Equal(t, admitted, admission)
+
+	// Types without a cap are always admitted
+	releaseOther, admission := limits.admit("data-processing", msg("x"), 1, hold)
+	assert.Equal(t, admitted, admission)
+	releaseOther()
+
+	// At the cap one message waits and the next is refused
+	_, admission = limits.admit("batch-import", msg("b"), 1, hold)
+	assert.Equal(t, parked, admission)
+	_, admission = limits.admit("batch-import", msg("c"), 1, hold)
+	assert.Equal(t, refused, admission)
+	assert.Equal(t, 1, held)
+
+	_, ok := limits.next()
+	assert.False(t, ok, "nothing has room yet")
+
+	release()
+	release()
+	next, ok := limits.next()
+	require.True(t, ok)
+	assert.Equal(t, "b", next.ID)
+	assert.Equal(t, 1, stopped)
+
+	// Closing hands back the waiting messages and refuses more
+	release, admission = limits.admit("batch-import", msg("d"), 1, hold)
+	require.Equal(t, admitted, admission)
+	_, admission = limits.admit("batch-import", msg("e"), 1, hold)
+	require.Equal(t, parked, admission)
+	assert.Equal(t, []queue.Message{msg("e")}, limits.close())
+	assert.Equal(t, 2, stopped)
+	_, admission = limits.admit("batch-import", msg("f"), 1, hold)
+	assert.Equal(t, refused, admission)
+	release()
+}
+
+func TestProcessor_SaturatedTypeDoesNotBlockOthers(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(10 * time.Millisecond)
+
+	unblock := make(chan struct{})
+	var slowRuns, fastRuns atomic.Int32
+	registry := jobs.NewRegistry()
+	registry.Register("batch-import", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		slowRuns.Add(1)
+		<-unblock
+		return &models.JobResult{Message: "imported"}, nil
+	}))
+	registry.Register("data-processing", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		fastRuns.Add(1)
+		return &models.JobResult{Message: "processed"}, nil
+	}))
+
+	p := NewProcessor(repo, q, registry, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		Concurrency:     2,
+		BatchSize:       1,
+		TypeConcurrency: map[string]int{"batch-import": 1},
+	})
+
+	send := func(jobType string) *models.Job {
+		job := &models.Job{Type: jobType}
+		require.NoError(t, repo.CreateJob(job))
+		require.NoError(t, q.SendMessage(context.Background(), job.ID.String()))
+		return job
+	}
+	var imports []*models.Job
+	for i := 0; i < 3; i++ {
+		imports = append(imports, send("batch-import"))
+	}
+	other := send("data-processing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Start(ctx)
+		close(done)
+	}()
+
+	// One import runs and the others wait, leaving a worker for other types
+	require.Eventually(t, func() bool {
+		job, err := repo.GetJob(other.ID.String())
+		return err == nil && job.Status == models.JobStatusCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), slowRuns.Load())
+	assert.Equal(t, int32(1), fastRuns.Load())
+
+	close(unblock)
+	require.Eventually(t, func() bool {
+		for _, imported := range imports {
+			job, err := repo.GetJob(imported.ID.String())
+			if err != nil || job.Status != models.JobStatusCompleted {
+				return false
+			}
+		}
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(3), slowRuns.Load())
+
+	cancel()
+	<-done
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestProcessor_LostLeaseDiscardsResult(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(10 * time.Millisecond)
+
+	finished := make(chan struct{})
+	registry := jobs.NewRegistry()
+	registry.Register("slow", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		defer close(finished)
+		// The lease runs out mid-run and another worker takes the job over
+		if err := repo.RenewJobLease(job.ID.String(), "worker-a", -time.Second); err != nil {
+			return nil, err
+		}
+		if _, err := repo.ClaimJob(job.ID.String(), "worker-b", time.Minute); err != nil {
+			return nil, err
+		}
+		return &models.JobResult{Message: "stale"}, nil
+	}))
+
+	p := NewProcessor(repo, q, registry, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		WorkerID:  "worker-a",
+		BatchSize: 1,
+	})
+
+	job := &models.Job{Type: "slow"}
+	require.NoError(t, repo.CreateJob(job))
+	require.NoError(t, q.SendMessage(context.Background(), job.ID.String()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Start(ctx)
+		close(done)
+	}()
+	<-finished
+	cancel()
+	<-done
+
+	found, err := repo.GetJob(job.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusProcessing, found.Status)
+	assert.Equal(t, "worker-b", found.WorkerID)
+	assert.Nil(t, found.Result)
+	assert.Equal(t, 1, q.Len(), "the message is left for the new holder")
+}
+
+// renewCounter counts lease renewals
+type renewCounter struct {
+	*repository.MemoryRepository
+	renewals atomic.Int32
+}
+
+func (r *renewCounter) RenewJobLease(id string, workerID string, lease time.Duration) error {
+	r.renewals.Add(1)
+	return r.MemoryRepository.RenewJobLease(id, workerID, lease)
+}
+
+func TestProcessor_RenewsLeaseWithoutVisibilityTimeout(t *testing.T) {
+	repo := &renewCounter{MemoryRepository: repository.NewMemoryRepository()}
+	q := queue.NewMemoryQueue(time.Minute, 0)
+	q.SetWaitTime(10 * time.Millisecond)
+
+	registry := jobs.NewRegistry()
+	registry.Register("slow", jobs.HandlerFunc(func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+		time.Sleep(100 * time.Millisecond)
+		return &models.JobResult{Message: "done"}, nil
+	}))
+
+	p := NewProcessor(repo, q, registry, slog.New(slog.NewTextHandler(io.Discard, nil)), Options{
+		HeartbeatInterval: 20 * time.Millisecond,
+	})
+	require.Equal(t, 20*time.Millisecond, p.heartbeatInterval)
+
+	job := &models.Job{Type: "slow"}
+	require.NoError(t, repo.CreateJob(job))
+	require.NoError(t, q.SendMessage(context.Background(), job.ID.String()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Start(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		found, err := repo.GetJob(job.ID.String())
+		return err == nil && found.Status == models.JobStatusCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.GreaterOrEqual(t, repo.renewals.Load(), int32(2))
+}
+
 func TestJobResult_Structure(t *testing.T) {
 	result := &models.JobResult{
 		ProcessedAt: time.Now(),
@@ -100,4 +337,5 @@ func TestJobStatus_Constants(t *testing.T) {
 	assert.Equal(t, models.JobStatus("processing"), models.JobStatusProcessing)
 	assert.Equal(t, models.JobStatus("completed"), models.JobStatusCompleted)
 	assert.Equal(t, models.JobStatus("failed"), models.JobStatusFailed)
+	assert.Equal(t, models.JobStatus("retrying"), models.JobStatusRetrying)
 }
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+)
+
+// defaultRetryBaseDelay is the backoff before the first retry
+const defaultRetryBaseDelay = 10 * time.Second
+
+// retryDelay returns the exponential backoff from base before the given
+// retry, with equal jitter so jobs that failed together don't retry
+// together. The delay is capped at the longest delay SQS can apply to a
+// message.
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < queue.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > queue.MaxDelay {
+		delay = queue.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{attempt: 1, base: 10 * time.Second},
+		{attempt: 2, base: 20 * time.Second},
+		{attempt: 3, base: 40 * time.Second},
+		{attempt: 10, base: queue.MaxDelay},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := retryDelay(defaultRetryBaseDelay, tt.attempt)
+			assert.GreaterOrEqual(t, delay, tt.base/2, "attempt %d", tt.attempt)
+			assert.LessOrEqual(t, delay, tt.base, "attempt %d", tt.attempt)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+)
+
+// typeBusyDelay is how long a message is put back on the queue for when its
+// type is at its cap and enough messages of the type are already waiting
+const typeBusyDelay = 10 * time.Second
+
+type admission int
+
+const (
+	admitted admission = iota
+	// parked messages wait for a job of their type to finish
+	parked
+	// refused messages must go back to the queue
+	refused
+)
+
+// typeLimits caps how many jobs of each type run at once. A message whose
+// type is at its cap waits here without holding a worker, so other types
+// keep running, until a worker whose job finished takes it on.
+type typeLimits struct {
+	mu      sync.Mutex
+	limits  map[string]int
+	running map[string]int
+	waiting map[string][]waitingMessage
+	closed  bool
+}
+
+type waitingMessage struct {
+	msg queue.Message
+	// stop ends the heartbeat keeping the message hidden while it waits
+	stop func()
+}
+
+func newTypeLimits(limits map[string]int) *typeLimits {
+	l := &typeLimits{
+		limits:  make(map[string]int, len(limits)),
+		running: make(map[string]int),
+		waiting: make(map[string][]waitingMessage),
+	}
+	for jobType, limit := range limits {
+		if limit > 0 {
+			l.limits[jobType] = limit
+		}
+	}
+	return l
+}
+
+// admit takes a slot for a job of jobType, returning the func that gives it
+// back. If the type is at its cap, msg is parked instead, with the heartbeat
+// started by hold keeping it hidden, unless maxWaiting messages of the type
+// already wait or the limits are closed; then it is refused.
+func (l *typeLimits) admit(jobType string, msg queue.Message, maxWaiting int, hold func() (stop func())) (func(), admission) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.limits[jobType]
+	if !ok {
+		return func() {}, admitted
+	}
+	if l.running[jobType] < limit {
+		l.running[jobType]++
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				l.mu.Lock()
+				defer l.mu.Unlock()
+				l.running[jobType]--
+			})
+		}, admitted
+	}
+	if l.closed || len(l.waiting[jobType]) >= maxWaiting {
+		return nil, refused
+	}
+	l.waiting[jobType] = append(l.waiting[jobType], waitingMessage{msg: msg, stop: hold()})
+	return nil, parked
+}
+
+// next takes the longest waiting message of a type that has room again
+func (l *typeLimits) next() (queue.Message, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for jobType, waiting := range l.waiting {
+		if len(waiting) == 0 || l.running[jobType] >= l.limits[jobType] {
+			continue
+		}
+		l.waiting[jobType] = waiting[1:]
+		waiting[0].stop()
+		return waiting[0].msg, true
+	}
+	return queue.Message{}, false
+}
+
+// close refuses any more waiting messages and returns those waiting, for
+// them to go back to the queue
+func (l *typeLimits) close() []queue.Message {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	var messages []queue.Message
+	for jobType, waiting := range l.waiting {
+		for _, w := range waiting {
+			w.stop()
+			messages = append(messages, w.msg)
+		}
+		delete(l.waiting, jobType)
+	}
+	return messages
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -11,7 +14,46 @@ type Config struct {
 	DatabaseURL  string
 	SQSEndpoint  string
 	SQSQueueURL  string
+	SQSDLQURL    string
 	AWSRegion    string
+
+	SQSHighQueueURL string
+	SQSLowQueueURL  string
+
+	DatabaseAutoMigrate bool
+
+	QueueBackend           string
+	QueueName              string
+	QueueVisibilityTimeout time.Duration
+	QueueMaxReceiveCount   int
+	QueuePriorityWeights   map[string]int
+
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
+
+	WorkerID                 string
+	WorkerConcurrency        int
+	WorkerBatchSize          int
+	WorkerTypeConcurrency    map[string]int
+	WorkerHeartbeatInterval  time.Duration
+	WorkerMaxJobRuntime      time.Duration
+	WorkerCancelPollInterval time.Duration
+
+	SchedulerSyncInterval time.Duration
+	SchedulerLeaseTTL     time.Duration
+
+	SchedulerPromoteInterval time.Duration
+
+	MetricsAddr string
+
+	TracingEnabled  bool
+	TracingEndpoint string
+	TracingExporter string
+
+	WebhookSecret       string
+	WebhookMaxAttempts  int
+	WebhookTimeout      time.Duration
+	WebhookPollInterval time.Duration
 }
 
 func Load() *Config {
@@ -21,7 +63,46 @@ func Load() *Config {
 		DatabaseURL: buildDatabaseURL(),
 		SQSEndpoint: getEnv("SQS_ENDPOINT", ""),
 		SQSQueueURL: getEnv("SQS_QUEUE_URL", ""),
+		SQSDLQURL:   getEnv("SQS_DLQ_URL", ""),
 		AWSRegion:   getEnv("AWS_REGION", "us-east-2"),
+
+		SQSHighQueueURL: getEnv("SQS_HIGH_QUEUE_URL", ""),
+		SQSLowQueueURL:  getEnv("SQS_LOW_QUEUE_URL", ""),
+
+		DatabaseAutoMigrate: getEnvBool("DB_AUTO_MIGRATE", true),
+
+		QueueBackend:           getEnv("QUEUE_BACKEND", "sqs"),
+		QueueName:              getEnv("QUEUE_NAME", "jobs"),
+		QueueVisibilityTimeout: getEnvDuration("QUEUE_VISIBILITY_TIMEOUT", 5*time.Minute),
+		QueueMaxReceiveCount:   getEnvInt("QUEUE_MAX_RECEIVE_COUNT", 3),
+		QueuePriorityWeights:   getEnvIntMap("QUEUE_PRIORITY_WEIGHTS"),
+
+		OutboxPollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		OutboxBatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 50),
+
+		WorkerID:                 getEnv("WORKER_ID", ""),
+		WorkerConcurrency:        getEnvInt("WORKER_CONCURRENCY", 10),
+		WorkerBatchSize:          getEnvInt("WORKER_BATCH_SIZE", 10),
+		WorkerTypeConcurrency:    getEnvIntMap("WORKER_TYPE_CONCURRENCY"),
+		WorkerHeartbeatInterval:  getEnvDuration("WORKER_HEARTBEAT_INTERVAL", time.Minute),
+		WorkerMaxJobRuntime:      getEnvDuration("WORKER_MAX_JOB_RUNTIME", 30*time.Minute),
+		WorkerCancelPollInterval: getEnvDuration("WORKER_CANCEL_POLL_INTERVAL", 5*time.Second),
+
+		SchedulerSyncInterval: getEnvDuration("SCHEDULER_SYNC_INTERVAL", 15*time.Second),
+		SchedulerLeaseTTL:     getEnvDuration("SCHEDULER_LEASE_TTL", 30*time.Second),
+
+		SchedulerPromoteInterval: getEnvDuration("SCHEDULER_PROMOTE_INTERVAL", 10*time.Second),
+
+		MetricsAddr: getEnv("METRICS_ADDR", ":9090"),
+
+		TracingEnabled:  getEnvBool("ENABLE_TRACING", false),
+		TracingEndpoint: getEnv("TRACING_ENDPOINT", "http://localhost:4318"),
+		TracingExporter: getEnv("TRACING_EXPORTER", "otlp"),
+
+		WebhookSecret:       getEnv("WEBHOOK_SECRET", ""),
+		WebhookMaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookTimeout:      getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookPollInterval: getEnvDuration("WEBHOOK_POLL_INTERVAL", time.Second),
 	}
 }
 
@@ -32,6 +113,49 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvIntMap parses "key=value,key=value" pairs, skipping malformed entries
+func getEnvIntMap(key string) map[string]int {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	result := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			continue
+		}
+		result[strings.TrimSpace(k)] = n
+	}
+	return result
+}
+
 func buildDatabaseURL() string {
 	// Check for DATABASE_URL first
 	if url := os.Getenv("DATABASE_URL"); url != "" {
@@ -46,4 +170,4 @@ func buildDatabaseURL() string {
 	name := getEnv("DB_NAME", "jobsdb")
 	
 	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, pass, host, port, name)
-}
\ No newline at end of file
+}
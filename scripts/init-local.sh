@@ -2,9 +2,11 @@
 
 echo "=== Initializing Local Environment ==="
 
-# Create SQS queue in LocalStack
-echo "Creating SQS queue..."
-aws --endpoint-url=http://localhost:4566 sqs create-queue --queue-name jobs-queue --region us-east-2
+# Create SQS queues in LocalStack
+echo "Creating SQS queues..."
+for queue in jobs-queue jobs-high-queue jobs-low-queue; do
+  aws --endpoint-url=http://localhost:4566 sqs create-queue --queue-name "$queue" --region us-east-2
+done
 
 # List queues to verify
 echo "Verifying queue creation..."
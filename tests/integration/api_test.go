@@ -37,7 +37,7 @@ func TestCreateJob(t *testing.T) {
 
 	payload := models.JobPayload{
 		Type: "data-processing",
-		Data: "test input data",
+		Data: models.JSONString("test input data"),
 	}
 
 	body, _ := json.Marshal(payload)
@@ -56,7 +56,7 @@ func TestCreateJob(t *testing.T) {
 	assert.NotEqual(t, uuid.Nil, job.ID)
 	assert.Equal(t, models.JobStatusPending, job.Status)
 	assert.Equal(t, "data-processing", job.Type)
-	assert.Equal(t, "test input data", job.Data)
+	assert.Equal(t, models.JSONString("test input data"), job.Data)
 }
 
 func TestGetJob(t *testing.T) {
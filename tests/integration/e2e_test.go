@@ -0,0 +1,315 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/api/handlers"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/jobs"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/models"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/outbox"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/queue"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/repository"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/scheduler"
+	"github.com/amayabdaniel/dab-aws-go-service-worker/internal/worker"
+)
+
+// visibilityTimeout is the stack queue's visibility timeout, which is also
+// its worker's job lease
+const visibilityTimeout = time.Second
+
+// stack runs the API, outbox relay, scheduler and worker in one process on
+// the in-memory repository and queue
+type stack struct {
+	server   *httptest.Server
+	repo     *repository.MemoryRepository
+	queue    *queue.MemoryQueue
+	registry *jobs.Registry
+	logger   *slog.Logger
+
+	ctx context.Context
+	wg  *sync.WaitGroup
+}
+
+type stackOption func(*stackOptions)
+
+type stackOptions struct {
+	withoutWorker bool
+}
+
+// withoutWorker leaves the worker to be started by startWorker, so a test
+// can act on the queue first
+func withoutWorker() stackOption {
+	return func(o *stackOptions) { o.withoutWorker = true }
+}
+
+func startStack(t *testing.T, registry *jobs.Registry, opts ...stackOption) *stack {
+	t.Helper()
+
+	var options stackOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := repository.NewMemoryRepository()
+	jobQueue := queue.NewMemoryQueue(visibilityTimeout, 3)
+	jobQueue.SetWaitTime(50 * time.Millisecond)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers.NewWithRepository(repo, jobQueue, logger).RegisterRoutes(router)
+	server := httptest.NewServer(router)
+
+	relay := outbox.NewRelay(repo, jobQueue, logger, 10*time.Millisecond, 10)
+	sched := scheduler.New(repo, logger, scheduler.Options{
+		SyncInterval: 50 * time.Millisecond,
+		Leases:       repo,
+		InstanceID:   "e2e",
+	})
+	promoter := scheduler.NewPromoter(repo, logger, 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &stack{
+		server:   server,
+		repo:     repo,
+		queue:    jobQueue,
+		registry: registry,
+		logger:   logger,
+		ctx:      ctx,
+		wg:       &sync.WaitGroup{},
+	}
+	s.run(relay.Start)
+	s.run(sched.Start)
+	s.run(promoter.Start)
+	if !options.withoutWorker {
+		s.startWorker()
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		s.wg.Wait()
+		server.Close()
+	})
+	return s
+}
+
+// startWorker starts the stack's worker, retrying failed jobs after 50ms
+func (s *stack) startWorker() {
+	processor := worker.NewProcessor(s.repo, s.queue, s.registry, s.logger, worker.Options{
+		WorkerID:          "e2e-worker",
+		Concurrency:       2,
+		VisibilityTimeout: visibilityTimeout,
+		RetryBaseDelay:    50 * time.Millisecond,
+	})
+	s.run(processor.Start)
+}
+
+func (s *stack) run(start func(context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		start(s.ctx)
+	}()
+}
+
+func (s *stack) createJob(t *testing.T, payload models.JobPayload) models.Job {
+	t.Helper()
+
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(s.server.URL+"/api/jobs", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var job models.Job
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	return job
+}
+
+func (s *stack) getJob(t *testing.T, id string) models.Job {
+	t.Helper()
+
+	resp, err := http.Get(s.server.URL + "/api/jobs/" + id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var job models.Job
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	return job
+}
+
+func (s *stack) waitForStatus(t *testing.T, id string, status models.JobStatus) models.Job {
+	t.Helper()
+
+	var job models.Job
+	require.Eventually(t, func() bool {
+		job = s.getJob(t, id)
+		return job.Status == status
+	}, 5*time.Second, 20*time.Millisecond, "job %s never reached %s", id, status)
+	return job
+}
+
+func TestEndToEnd_JobCompletes(t *testing.T) {
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.Register("echo", jobs.HandlerFunc(
+		func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			return &models.JobResult{ProcessedAt: time.Now(), Message: "echo: " + job.Data.String()}, nil
+		})))
+
+	s := startStack(t, registry)
+	created := s.createJob(t, models.JobPayload{Type: "echo", Data: models.JSONString("hello")})
+	assert.Equal(t, models.JobStatusPending, created.Status)
+
+	job := s.waitForStatus(t, created.ID.String(), models.JobStatusCompleted)
+	require.NotNil(t, job.Result)
+	assert.Equal(t, `echo: "hello"`, job.Result.Message)
+	assert.Equal(t, 1, job.Attempts)
+	require.Len(t, job.AttemptHistory, 1)
+	assert.Empty(t, job.AttemptHistory[0].Error)
+
+	// The message is deleted once the job is done
+	assert.Eventually(t, func() bool { return s.queue.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestEndToEnd_PermanentFailure(t *testing.T) {
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.Register("broken", jobs.HandlerFunc(
+		func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			return nil, jobs.Permanent(errors.New("bad input"))
+		})))
+
+	s := startStack(t, registry)
+
+	broken := s.createJob(t, models.JobPayload{Type: "broken", Data: models.JSONString("x")})
+	unknown := s.createJob(t, models.JobPayload{Type: "unregistered", Data: models.JSONString("x")})
+
+	job := s.waitForStatus(t, broken.ID.String(), models.JobStatusFailed)
+	assert.Contains(t, job.Error, "bad input")
+	assert.Equal(t, 1, job.Attempts, "permanent errors are not retried")
+
+	job = s.waitForStatus(t, unknown.ID.String(), models.JobStatusFailed)
+	assert.Contains(t, job.Error, jobs.ErrNoHandler.Error())
+}
+
+func TestEndToEnd_RetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.Register("flaky", jobs.HandlerFunc(
+		func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			if calls.Add(1) < 3 {
+				return nil, errors.New("upstream unavailable")
+			}
+			return &models.JobResult{ProcessedAt: time.Now(), Message: "done"}, nil
+		})))
+
+	s := startStack(t, registry)
+	created := s.createJob(t, models.JobPayload{Type: "flaky", Data: models.JSONString("x"), MaxAttempts: 3})
+
+	job := s.waitForStatus(t, created.ID.String(), models.JobStatusCompleted)
+	assert.Equal(t, 3, job.Attempts)
+	require.Len(t, job.AttemptHistory, 3)
+	assert.Contains(t, job.AttemptHistory[0].Error, "upstream unavailable")
+	assert.Contains(t, job.AttemptHistory[1].Error, "upstream unavailable")
+	assert.Empty(t, job.AttemptHistory[2].Error)
+	assert.Empty(t, job.Error)
+
+	// Out of attempts the job fails for good
+	calls.Store(-10)
+	created = s.createJob(t, models.JobPayload{Type: "flaky", Data: models.JSONString("x"), MaxAttempts: 2})
+	job = s.waitForStatus(t, created.ID.String(), models.JobStatusFailed)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Contains(t, job.Error, "upstream unavailable")
+	assert.Eventually(t, func() bool { return s.queue.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestEndToEnd_RedeliveredAfterVisibilityTimeout(t *testing.T) {
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.Register("echo", jobs.HandlerFunc(
+		func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			return &models.JobResult{ProcessedAt: time.Now(), Message: "echo"}, nil
+		})))
+
+	s := startStack(t, registry, withoutWorker())
+	created := s.createJob(t, models.JobPayload{Type: "echo", Data: models.JSONString("x")})
+
+	// A worker receives and claims the job, then dies without finishing it
+	var messages []queue.Message
+	require.Eventually(t, func() bool {
+		var err error
+		messages, err = s.queue.ReceiveMessages(context.Background(), 1)
+		return err == nil && len(messages) == 1
+	}, time.Second, 10*time.Millisecond)
+	_, err := s.repo.ClaimJob(created.ID.String(), "dead-worker", visibilityTimeout/2)
+	require.NoError(t, err)
+
+	// Once the message's visibility timeout and the dead worker's lease run
+	// out, another worker takes the job over
+	s.startWorker()
+	job := s.waitForStatus(t, created.ID.String(), models.JobStatusCompleted)
+	assert.Equal(t, "e2e-worker", job.WorkerID)
+	assert.Equal(t, 2, job.Attempts, "the dead worker's claim counts as an attempt")
+	assert.Eventually(t, func() bool { return s.queue.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestEndToEnd_ScheduleFires(t *testing.T) {
+	registry := jobs.NewRegistry()
+	require.NoError(t, registry.Register("tick", jobs.HandlerFunc(
+		func(ctx context.Context, job *models.Job) (*models.JobResult, error) {
+			return &models.JobResult{ProcessedAt: time.Now(), Message: "tick: " + job.Data.String()}, nil
+		})))
+
+	s := startStack(t, registry)
+
+	body, _ := json.Marshal(models.SchedulePayload{
+		Name:            "every-second",
+		Cron:            "* * * * * *",
+		JobType:         "tick",
+		PayloadTemplate: `"{{.Schedule}}"`,
+	})
+	resp, err := http.Post(s.server.URL+"/api/schedules", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var schedule models.Schedule
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&schedule))
+
+	// The scheduler picks the schedule up on its next sync and fires it
+	var run models.ScheduleRun
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(s.server.URL + "/api/schedules/" + schedule.ID.String() + "/runs")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		var runs struct {
+			Runs []models.ScheduleRun `json:"runs"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&runs) != nil || len(runs.Runs) == 0 {
+			return false
+		}
+		run = runs.Runs[len(runs.Runs)-1]
+		return true
+	}, 5*time.Second, 50*time.Millisecond, "schedule never fired")
+	require.NotNil(t, run.JobID)
+	assert.False(t, run.CatchUp)
+
+	job := s.waitForStatus(t, run.JobID.String(), models.JobStatusCompleted)
+	assert.Equal(t, "tick", job.Type)
+	assert.Equal(t, `tick: "every-second"`, job.Result.Message)
+}